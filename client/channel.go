@@ -0,0 +1,220 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server"
+	"github.com/gophergala2016/gobotgo/websocket"
+)
+
+// ErrNoChannel is returned by Client.Channel for games not played on a server
+var ErrNoChannel = errors.New("Game has no channel")
+
+// Channel is a connection to a game's channel, which pushes everything that happens
+// in the game as it happens. Players can also play over it, and spectators can only chat.
+type Channel struct {
+	conn   *websocket.Conn
+	events chan server.Event
+	closed chan struct{}
+	close  sync.Once
+
+	mu sync.Mutex
+	// replies wait for the answers to the commands sent, by ID
+	replies map[int]chan *server.Error
+	next    int
+	// err is why the connection ended, once it has
+	err error
+}
+
+// Channel connects to the client's game channel. Only games played over HTTP have one.
+func (c *Client) Channel() (*Channel, error) {
+	h, ok := c.transport.(*HTTP)
+	if !ok {
+		return nil, ErrNoChannel
+	}
+	return h.channel(h.playURL(c.id, "ws"))
+}
+
+// Watch connects to the channel of game number n on the server at url as a spectator
+func Watch(url string, n uint64) (*Channel, error) {
+	return NewHTTP(url, "").channel(fmt.Sprintf("%s/api/v2/games/%d/ws", url, n))
+}
+
+func (h *HTTP) channel(url string) (*Channel, error) {
+	header := http.Header{}
+	if h.key != "" {
+		header.Set(server.KeyHeader, h.key)
+	}
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(url, "http"), header)
+	if err, ok := err.(*websocket.DialError); ok {
+		var e server.Error
+		if json.Unmarshal(err.Body, &e) == nil {
+			return nil, e
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	ch := &Channel{
+		conn:    conn,
+		events:  make(chan server.Event),
+		closed:  make(chan struct{}),
+		replies: map[int]chan *server.Error{},
+	}
+	in := make(chan server.Event)
+	go ch.read(in)
+	go ch.pump(in)
+	return ch, nil
+}
+
+// Events receives the events of the game, starting with its state,
+// and is closed once the connection ends
+func (ch *Channel) Events() <-chan server.Event {
+	return ch.events
+}
+
+// read hands replies to the commands waiting for them and every other event to pump
+func (ch *Channel) read(in chan<- server.Event) {
+	defer close(in)
+	for {
+		var e server.Event
+		if err := ch.conn.ReadJSON(&e); err != nil {
+			ch.fail(err)
+			return
+		}
+		if e.Type != server.EventReply {
+			select {
+			case in <- e:
+			case <-ch.closed:
+				ch.fail(websocket.ErrClosed)
+				return
+			}
+			continue
+		}
+		ch.mu.Lock()
+		reply, ok := ch.replies[e.ID]
+		delete(ch.replies, e.ID)
+		ch.mu.Unlock()
+		if ok {
+			reply <- e.Error
+		}
+	}
+}
+
+// pump queues events until they are received from Events,
+// so replies are not held up by events nobody has read yet
+func (ch *Channel) pump(in <-chan server.Event) {
+	defer close(ch.events)
+	var queue []server.Event
+	for in != nil || len(queue) > 0 {
+		var out chan<- server.Event
+		var next server.Event
+		if len(queue) > 0 {
+			out, next = ch.events, queue[0]
+		}
+		select {
+		case e, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			queue = append(queue, e)
+		case out <- next:
+			queue = queue[1:]
+		case <-ch.closed:
+			return
+		}
+	}
+}
+
+// fail ends the commands waiting for replies with err
+func (ch *Channel) fail(err error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.err = err
+	for id, reply := range ch.replies {
+		close(reply)
+		delete(ch.replies, id)
+	}
+}
+
+// command sends c and waits for its reply. Errors are returned as for the HTTP Transport.
+func (ch *Channel) command(c server.Command) error {
+	ch.mu.Lock()
+	if ch.err != nil {
+		defer ch.mu.Unlock()
+		return ch.err
+	}
+	ch.next++
+	c.ID = ch.next
+	reply := make(chan *server.Error, 1)
+	ch.replies[c.ID] = reply
+	ch.mu.Unlock()
+	if err := ch.conn.WriteJSON(c); err != nil {
+		ch.mu.Lock()
+		delete(ch.replies, c.ID)
+		ch.mu.Unlock()
+		return err
+	}
+	e, ok := <-reply
+	switch {
+	case !ok:
+		ch.mu.Lock()
+		defer ch.mu.Unlock()
+		return ch.err
+	case e == nil:
+		return nil
+	}
+	if err := e.Unwrap(); err != nil {
+		return err
+	}
+	return *e
+}
+
+func (ch *Channel) move(move []int) error {
+	m, err := json.Marshal(move)
+	if err != nil {
+		return err
+	}
+	return ch.command(server.Command{Action: "move", Move: m})
+}
+
+func (ch *Channel) Move(p game.Position) error {
+	return ch.move([]int{p.X, p.Y})
+}
+
+func (ch *Channel) Pass() error {
+	return ch.move([]int{})
+}
+
+// Resign ends the game with a win for the opponent, which is sent as a game over event
+func (ch *Channel) Resign() error {
+	return ch.command(server.Command{Action: "resign"})
+}
+
+// Undo asks to take back the last turn when answer is empty,
+// or answers the opponent's request with "accept" or "reject"
+func (ch *Channel) Undo(answer string) error {
+	return ch.command(server.Command{Action: "undo", Answer: answer})
+}
+
+// Chat sends a message to the players and spectators of the game
+func (ch *Channel) Chat(message string) error {
+	return ch.command(server.Command{Action: "chat", Message: message})
+}
+
+// Close ends the connection, closing Events
+func (ch *Channel) Close() error {
+	var err error
+	ch.close.Do(func() {
+		close(ch.closed)
+		err = ch.conn.Close()
+	})
+	return err
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server"
+)
+
+// nextEvent receives events from ch until one of type typ
+func nextEvent(t *testing.T, ch *Channel, typ server.EventType) server.Event {
+	for {
+		select {
+		case e, ok := <-ch.Events():
+			if !ok {
+				t.Fatalf("channel closed waiting for %s", typ)
+			}
+			if e.Type == typ {
+				return e
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", typ)
+		}
+	}
+}
+
+func TestChannel(t *testing.T) {
+	ts := httptest.NewServer(server.MuxerAPI(server.NewMemoryStore()))
+	defer ts.Close()
+	p1, p2 := newClient(t, ts.URL, game.Black), newClient(t, ts.URL, game.White)
+	black, err := p1.Channel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer black.Close()
+	white, err := p2.Channel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer white.Close()
+	spectator, err := Watch(ts.URL, p1.Game())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spectator.Close()
+	if e := nextEvent(t, spectator, server.EventState); e.Game != p1.Game() || e.State.CurrentPlayer != game.Black {
+		t.Errorf("unexpected state %+v", e)
+	}
+
+	if err := white.Move(game.Position{0, 2}); err != game.ErrWrongPlayer {
+		t.Errorf("expected '%s', got '%v'", game.ErrWrongPlayer, err)
+	}
+	testError(t, black.Move(game.Position{0, 2}))
+	if e := nextEvent(t, white, server.EventMove); e.Player != game.Black || *e.Position != (game.Position{0, 2}) {
+		t.Errorf("unexpected move %+v", e)
+	}
+	// Moves played over HTTP are pushed too
+	testError(t, p2.Move(game.Position{1, 0}))
+	nextEvent(t, spectator, server.EventMove)
+	if e := nextEvent(t, spectator, server.EventMove); e.Player != game.White || e.Turns != 2 {
+		t.Errorf("unexpected move %+v", e)
+	}
+
+	if err := spectator.Move(game.Position{3, 3}); err == nil {
+		t.Error("expected spectators to be refused moves")
+	}
+	testError(t, spectator.Chat("go bot go"))
+	if e := nextEvent(t, black, server.EventChat); e.Message != "go bot go" || e.From != "spectator" {
+		t.Errorf("unexpected chat %+v", e)
+	}
+
+	testError(t, white.Resign())
+	if e := nextEvent(t, spectator, server.EventGameOver); e.Result == nil || e.Result.Winner != game.Black {
+		t.Errorf("unexpected game over %+v", e)
+	}
+	testOver(t, black.Pass())
+
+	black.Close()
+	if err := black.Chat("bye"); err == nil {
+		t.Error("expected a closed channel to fail")
+	}
+	for range black.Events() {
+	}
+
+	if _, err := connect(t, NewLocal(), game.Black).Channel(); err != ErrNoChannel {
+		t.Errorf("expected '%s', got '%v'", ErrNoChannel, err)
+	}
+}
@@ -1,59 +1,76 @@
 package client
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"time"
 
 	"github.com/gophergala2016/gobotgo/game"
 	"github.com/gophergala2016/gobotgo/server"
 )
 
-// Client connects to a URL and begins a game
+// Client is a player in a game, played over a Transport.
+// A Client must not be used by more than one goroutine at a time.
 type Client struct {
-	client http.Client
-	url    string
-	id     server.GameID
-	player game.Color
-	state  game.PublicState
+	transport Transport
+	id        server.GameID
+	player    game.Color
+	state     State
 }
 
-func (c *Client) playURL(s string) string {
-	return fmt.Sprintf("%s/api/v1/game/play/%d/%s", c.url, c.id, s)
+// New starts an anonymous game at url
+func New(url string) (*Client, error) {
+	return NewPlayer(url, "")
+}
+
+// NewPlayer starts a game at url as the registered player with the API key
+func NewPlayer(url, key string) (*Client, error) {
+	return NewGame(url, key, server.DefaultSettings)
+}
+
+// NewGame starts a game with the settings at url, as the registered player
+// with the API key if it is set. It is paired with a player asking for the same settings.
+func NewGame(url, key string, s server.Settings) (*Client, error) {
+	return Connect(NewHTTP(url, key), s)
 }
 
-func (c *Client) retrieve(s string, v interface{}) error {
-	resp, err := c.client.Get(c.url + "/api/v1/game/" + s)
+// Challenge opens a game with the settings at url that only the registered player
+// opponent can join, as the registered player with the API key, who plays Black
+func Challenge(url, key, opponent string, s server.Settings) (*Client, error) {
+	h := NewHTTP(url, key)
+	v := s.Query()
+	v.Set("opponent", opponent)
+	id, color, err := h.lobby("challenge", v)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
-	dec := json.NewDecoder(resp.Body)
-
-	return dec.Decode(v)
+	return seat(h, id, color)
 }
 
-func New(url string) (*Client, error) {
-	c := Client{
-		client: http.Client{Timeout: time.Minute * 10},
-		url:    url,
+// Join takes White in the open game n at url, as the registered player with the API key if it is set
+func Join(url, key string, n uint64) (*Client, error) {
+	h := NewHTTP(url, key)
+	id, color, err := h.lobby(fmt.Sprintf("join/%d", n), nil)
+	if err != nil {
+		return nil, err
 	}
-	v := struct {
-		ID    server.GameID
-		Color game.Color
-	}{}
-	if err := c.retrieve("start/", &v); err != nil {
+	return seat(h, id, color)
+}
+
+// Connect starts a game with the settings over t
+func Connect(t Transport, s server.Settings) (*Client, error) {
+	id, color, err := t.Start(s)
+	if err != nil {
 		return nil, err
 	}
-	c.id = v.ID
-	c.player = v.Color
+	return seat(t, id, color)
+}
 
+// seat is the client of the player seated in a game over t
+func seat(t Transport, id server.GameID, color game.Color) (*Client, error) {
+	c := Client{transport: t, id: id, player: color}
 	if err := c.loadState(); err != nil {
 		return nil, err
 	}
-
 	return &c, nil
 }
 
@@ -62,71 +79,77 @@ func (c *Client) ID() server.GameID {
 }
 
 func (c *Client) loadState() error {
-	ps := game.PublicState{}
-	resp, err := c.client.Get(c.playURL("state"))
+	s, err := c.transport.State(c.id)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if err := json.NewDecoder(resp.Body).Decode(&ps); err != nil {
-		return err
-	}
-	c.state = ps
+	c.state = s
 	return nil
 }
 
-func (c *Client) move(m []int) error {
-	data, err := json.Marshal(m)
-	if err != nil {
-		return err
+// reload loads the state after an action, which is loaded even when the action ended the game
+func (c *Client) reload(err error) error {
+	if stateErr := c.loadState(); err == nil {
+		err = stateErr
 	}
-	resp, err := c.client.Post(
-		c.playURL("move"),
-		"application/json",
-		bytes.NewBuffer(data),
-	)
+	return err
+}
+
+func (c *Client) Move(p game.Position) error {
+	return c.reload(c.transport.Move(c.id, p))
+}
+
+func (c *Client) Pass() error {
+	return c.reload(c.transport.Pass(c.id))
+}
+
+// Resign ends the game with a win for the opponent
+func (c *Client) Resign() (game.Result, error) {
+	result, err := c.transport.Resign(c.id)
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	var response string
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return err
+		return result, err
 	}
+	return result, c.loadState()
+}
 
-	err = c.loadState()
-
-	switch response {
-	case "valid":
-		return nil
-	case game.ErrSpotNotEmpty.Error():
-		return game.ErrSpotNotEmpty
-	case game.ErrOutOfBounds.Error():
-		return game.ErrOutOfBounds
-	case game.ErrWrongPlayer.Error():
-		return game.ErrWrongPlayer
-	case game.ErrRepeatState.Error():
-		return game.ErrRepeatState
-	case game.ErrSelfCapture.Error():
-		return game.ErrSelfCapture
-	case game.ErrGameOver.Error():
-		return game.ErrGameOver
-	default:
-		return fmt.Errorf("Bad request: %s", response)
+// Result returns how the game ended, and false while it is still being played
+func (c *Client) Result() (game.Result, bool) {
+	if c.state.Result == nil {
+		return game.Result{}, false
 	}
+	return *c.state.Result, true
+}
 
-	if err != nil {
-		return err
-	}
-	return nil
+// RequestUndo asks the opponent to take back the last turn
+func (c *Client) RequestUndo() error {
+	return c.undo("")
 }
 
-func (c *Client) Move(p game.Position) error {
-	return c.move([]int{p.X, p.Y})
+// AcceptUndo agrees to the opponent's request to take back the last turn
+func (c *Client) AcceptUndo() error {
+	return c.undo("accept")
 }
 
-func (c *Client) Pass() error {
-	return c.move([]int{})
+// RejectUndo refuses the opponent's request to take back the last turn
+func (c *Client) RejectUndo() error {
+	return c.undo("reject")
+}
+
+// UndoRequested reports whether the opponent has asked to take back the last turn
+func (c *Client) UndoRequested() bool {
+	return c.state.UndoRequest == c.Opponent()
+}
+
+func (c *Client) undo(answer string) error {
+	if err := c.transport.Undo(c.id, answer); err != nil {
+		return err
+	}
+	return c.loadState()
+}
+
+// Game returns the number spectators watch the game by
+func (c *Client) Game() uint64 {
+	return c.state.Game
 }
 
 func (c *Client) Color() game.Color {
@@ -137,17 +160,19 @@ func (c *Client) Opponent() game.Color {
 	return c.player.Opponent()
 }
 
-func (c *Client) Wait() error {
-	_, err := c.client.Get(c.playURL("wait"))
-	err2 := c.loadState()
-	switch {
-	case err != nil:
+// Wait returns once it is the player's turn, game.ErrGameOver once the game has ended,
+// or ctx.Err() if ctx ends first
+func (c *Client) Wait(ctx context.Context) error {
+	err := c.transport.Wait(ctx, c.id)
+	if err != nil && err == ctx.Err() {
 		return err
-	case err2 != nil:
-		return err2
-	default:
-		return nil
 	}
+	return c.reload(err)
+}
+
+// History retrieves the record of the game, which game.Replay can step through
+func (c *Client) History() (game.Record, error) {
+	return c.transport.History(c.id)
 }
 
 func (c *Client) CurrentPlayer() game.Color {
@@ -166,3 +191,35 @@ func (c *Client) BlackStones() game.Stones {
 func (c *Client) WhiteStones() game.Stones {
 	return c.state.White
 }
+
+// LastMove returns the most recent stone played
+func (c *Client) LastMove() game.LastMove {
+	return c.state.LastMove
+}
+
+// Size returns the width of the board
+func (c *Client) Size() int {
+	return len(c.state.Board)
+}
+
+// Rules returns the rules the game is being played under
+func (c *Client) Rules() game.Rules {
+	return c.state.Rules
+}
+
+// Clock returns the time player had left when the state was loaded, and false if the game is untimed
+func (c *Client) Clock(player game.Color) (game.Clock, bool) {
+	switch {
+	case c.state.Clocks == nil:
+		return game.Clock{}, false
+	case player == game.Black:
+		return c.state.Clocks.Black, true
+	default:
+		return c.state.Clocks.White, true
+	}
+}
+
+// Score counts the current board under the rules of the game
+func (c *Client) Score() game.Score {
+	return c.state.Rules.Score(c.State(), c.state.Black, c.state.White)
+}
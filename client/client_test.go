@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"net/http/httptest"
 	"runtime"
 	"testing"
@@ -10,13 +11,18 @@ import (
 	"github.com/gophergala2016/gobotgo/server"
 )
 
-func newClient(t *testing.T, URL string, ID server.GameID, color game.Color) *Client {
-	c, err := New(URL)
+func newClient(t *testing.T, URL string, color game.Color) *Client {
+	return newPlayer(t, URL, "", color)
+}
+
+func newPlayer(t *testing.T, URL, key string, color game.Color) *Client {
+	return connect(t, NewHTTP(URL, key), color)
+}
+
+func connect(t *testing.T, tr Transport, color game.Color) *Client {
+	c, err := Connect(tr, server.DefaultSettings)
 	if err != nil {
-		t.Fatal("failed to initialize client: '%s'", err)
-	}
-	if c.ID() != ID {
-		t.Fatalf("expected ID %d, got %d", ID, c.ID())
+		t.Fatalf("failed to initialize client: '%s'", err)
 	}
 	if c.Color() != color {
 		t.Fatalf("Expected color %s, got %s", color, c.Color())
@@ -43,7 +49,11 @@ func move(t *testing.T, c *Client, x, y int) func() {
 }
 
 func wait(t *testing.T, c *Client) func() {
-	return func() { testError(t, c.Wait()) }
+	return func() { testError(t, c.Wait(context.Background())) }
+}
+
+func waitOver(t *testing.T, c *Client) func() {
+	return func() { testOver(t, c.Wait(context.Background())) }
 }
 
 func passOver(t *testing.T, c *Client) func() {
@@ -59,15 +69,33 @@ func gameOver(t *testing.T, c *Client) func() {
 		testOver(t, c.Pass())
 		testOver(t, c.Move(game.Position{0, 2}))
 		testOver(t, c.Move(game.Position{0, 2}))
-		testError(t, c.Wait())
+		testOver(t, c.Wait(context.Background()))
 	}
 }
 
-// assumes gobot/server is well formed
+// transports start a game between two players over each Transport, which should behave the same
+var transports = map[string]func(t *testing.T) (*Client, *Client){
+	"http": func(t *testing.T) (*Client, *Client) {
+		// assumes gobot/server is well formed
+		ts := httptest.NewServer(server.MuxerAPI(server.NewMemoryStore()))
+		return newClient(t, ts.URL, game.Black), newClient(t, ts.URL, game.White)
+	},
+	"local": func(t *testing.T) (*Client, *Client) {
+		l := NewLocal()
+		return connect(t, l, game.Black), connect(t, l, game.White)
+	},
+}
+
 func TestBasic(t *testing.T) {
-	ts := httptest.NewServer(server.MuxerAPIv1())
-	p1 := newClient(t, ts.URL, 1, game.Black)
-	p2 := newClient(t, ts.URL, 2, game.White)
+	for name, start := range transports {
+		t.Run(name, func(t *testing.T) {
+			testBasic(t, start)
+		})
+	}
+}
+
+func testBasic(t *testing.T, start func(t *testing.T) (*Client, *Client)) {
+	p1, p2 := start(t)
 
 	// white can't play yet
 	if err := p2.Move(game.Position{0, 2}); err != game.ErrWrongPlayer {
@@ -89,14 +117,16 @@ func TestBasic(t *testing.T) {
 	v.After(6, wait(t, p2))
 	v.Before(5, pass(t, p1))
 	v.Verify(time.Second)
-	v.After(8, wait(t, p1))
+	v.After(8, waitOver(t, p1))
 	v.Before(7, passOver(t, p2))
 	v.Verify(time.Second)
-	v.After(9, gameOver(t, p1))
-	v.After(9, gameOver(t, p2))
+	// Each Client is only used by one goroutine at a time
 	v.After(9, gameOver(t, p1))
 	v.After(9, gameOver(t, p2))
 	v.Verify(time.Second)
+	v.After(10, gameOver(t, p1))
+	v.After(10, gameOver(t, p2))
+	v.Verify(time.Second)
 
 	testPosition(t, p1, game.White, 1, 0)
 	testPosition(t, p2, game.White, 1, 0)
@@ -105,6 +135,174 @@ func TestBasic(t *testing.T) {
 func testPosition(t *testing.T, c *Client, p game.Color, x, y int) {
 	found := c.state.Board[x][y]
 	if p != found {
-		t.Errorf("at %d-%d expected %s, found %s", p, found)
+		t.Errorf("at %d-%d expected %s, found %s", x, y, p, found)
+	}
+}
+
+func TestResign(t *testing.T) {
+	for name, start := range transports {
+		t.Run(name, func(t *testing.T) {
+			testResign(t, start)
+		})
+	}
+}
+
+func testResign(t *testing.T, start func(t *testing.T) (*Client, *Client)) {
+	p1, p2 := start(t)
+	testError(t, p1.Move(game.Position{2, 2}))
+	result, err := p1.Resign()
+	testError(t, err)
+	if result.Winner != game.White || result.Reason != game.ResignReason {
+		t.Errorf("expected White to win by resignation, got %+v", result)
+	}
+	testOver(t, p2.Wait(context.Background()))
+	if r, ok := p2.Result(); !ok || r != result {
+		t.Errorf("expected result %+v after waiting, got %+v", result, r)
+	}
+	// The resignation cannot be taken back
+	testOver(t, p1.RequestUndo())
+	testOver(t, p2.AcceptUndo())
+	// The final board is still available
+	testPosition(t, p2, game.Black, 2, 2)
+}
+
+func TestTypedErrors(t *testing.T) {
+	for name, start := range transports {
+		t.Run(name, func(t *testing.T) {
+			testTypedErrors(t, start)
+		})
+	}
+}
+
+func testTypedErrors(t *testing.T, start func(t *testing.T) (*Client, *Client)) {
+	p1, p2 := start(t)
+	if err := p1.RequestUndo(); err != game.ErrNoUndo {
+		t.Errorf("expected '%s', got '%v'", game.ErrNoUndo, err)
+	}
+	err := p1.AcceptUndo()
+	if e, ok := err.(server.Error); !ok || e.Code != server.CodeNoUndoRequest {
+		t.Errorf("expected %s error, got '%v'", server.CodeNoUndoRequest, err)
+	}
+	testError(t, p1.Move(game.Position{2, 2}))
+	testError(t, p1.RequestUndo())
+	testError(t, p2.Wait(context.Background()))
+	if !p2.UndoRequested() {
+		t.Fatal("expected White to see the undo request")
+	}
+	testError(t, p2.AcceptUndo())
+	testPosition(t, p2, game.None, 2, 2)
+	if p2.CurrentPlayer() != game.Black {
+		t.Errorf("expected Black to play again, got %s", p2.CurrentPlayer())
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	for name, start := range transports {
+		t.Run(name, func(t *testing.T) {
+			_, p2 := start(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			// Black never moves, so White waits until the context ends
+			if err := p2.Wait(ctx); err != context.DeadlineExceeded {
+				t.Errorf("expected '%s', got '%v'", context.DeadlineExceeded, err)
+			}
+			if p2.CurrentPlayer() != game.Black {
+				t.Errorf("expected Black to play, got %s", p2.CurrentPlayer())
+			}
+		})
+	}
+}
+
+func TestRegisteredPlayer(t *testing.T) {
+	ts := httptest.NewServer(server.MuxerAPI(server.NewMemoryStore()))
+	bot, key, err := Register(ts.URL, server.Player{Name: "gobot", Kind: server.Bot, Language: "Go"})
+	testError(t, err)
+	if bot.ID != 1 || bot.Name != "gobot" || bot.Kind != server.Bot || key == "" {
+		t.Fatalf("unexpected registration %+v %q", bot, key)
+	}
+	if _, _, err := Register(ts.URL, server.Player{Name: "gobot"}); err == nil {
+		t.Error("expected a second gobot to be refused")
+	}
+	p1 := newPlayer(t, ts.URL, key, game.Black)
+	p2 := newClient(t, ts.URL, game.White)
+	if p1.ID() == p2.ID() {
+		t.Errorf("expected players to have their own GameID, both have %s", p1.ID())
+	}
+	testError(t, p1.Move(game.Position{2, 2}))
+
+	// The GameID alone does not play a registered player's seat
+	p1.transport.(*HTTP).key = ""
+	err = p1.Pass()
+	if e, ok := err.(server.Error); !ok || e.Code != server.CodeUnauthorized {
+		t.Errorf("expected %s error, got '%v'", server.CodeUnauthorized, err)
+	}
+}
+
+func TestNewGame(t *testing.T) {
+	ts := httptest.NewServer(server.MuxerAPI(server.NewMemoryStore()))
+	defer ts.Close()
+	s := server.DefaultSettings
+	s.Size = 9
+	s.Rules.Komi = 0.5
+	p1, err := NewGame(ts.URL, "", s)
+	testError(t, err)
+	// A player asking for the default settings is not paired with them
+	p2 := newClient(t, ts.URL, game.Black)
+	p3, err := NewGame(ts.URL, "", s)
+	testError(t, err)
+	if p1 == nil || p3 == nil || p3.Color() != game.White {
+		t.Fatalf("expected the second 9x9 player to be White")
+	}
+	for _, c := range []*Client{p1, p3} {
+		if c.Size() != 9 || c.Rules().Komi != 0.5 {
+			t.Errorf("expected 9x9 with komi 0.5, got %dx%[1]d with komi %g", c.Size(), c.Rules().Komi)
+		}
+	}
+	if p2.Size() != 19 {
+		t.Errorf("expected 19x19, got %d", p2.Size())
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	ts := httptest.NewServer(server.MuxerAPI(server.NewMemoryStore()))
+	defer ts.Close()
+	_, annKey, err := Register(ts.URL, server.Player{Name: "ann"})
+	testError(t, err)
+	_, bobKey, err := Register(ts.URL, server.Player{Name: "bob"})
+	testError(t, err)
+	p1, err := Challenge(ts.URL, annKey, "bob", server.DefaultSettings)
+	testError(t, err)
+	if p1 == nil || p1.Color() != game.Black {
+		t.Fatalf("expected ann to play Black, got %+v", p1)
+	}
+	// Only bob can take the seat
+	if _, err := Join(ts.URL, "", p1.Game()); err == nil {
+		t.Error("expected an anonymous player to be refused")
+	}
+	p2, err := Join(ts.URL, bobKey, p1.Game())
+	testError(t, err)
+	if p2 == nil || p2.Color() != game.White || p2.Game() != p1.Game() {
+		t.Fatalf("expected bob to play White in game %d, got %+v", p1.Game(), p2)
+	}
+	testError(t, p1.Move(game.Position{2, 2}))
+	testError(t, p2.Wait(context.Background()))
+	testPosition(t, p2, game.Black, 2, 2)
+}
+
+func TestBadSettings(t *testing.T) {
+	ts := httptest.NewServer(server.MuxerAPI(server.NewMemoryStore()))
+	defer ts.Close()
+	handicap := server.DefaultSettings
+	handicap.Size = 7
+	handicap.Rules.Handicap = game.Handicap{Stones: 2}
+	small := server.DefaultSettings
+	small.Size = 1
+	for _, s := range []server.Settings{handicap, small} {
+		for name, tr := range map[string]Transport{"http": NewHTTP(ts.URL, ""), "local": NewLocal()} {
+			_, err := Connect(tr, s)
+			if e, ok := err.(server.Error); !ok || e.Code != server.CodeBadRules {
+				t.Errorf("%s %dx%[2]d: expected %s error, got '%v'", name, s.Size, server.CodeBadRules, err)
+			}
+		}
 	}
 }
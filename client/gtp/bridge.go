@@ -0,0 +1,165 @@
+package gtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/client"
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// ErrResigned is returned by Play when the engine resigns, after the game has been resigned on the server
+var ErrResigned = errors.New("gtp: engine resigned")
+
+// IllegalMoveError is returned by Play when the server rejects the engine's move
+type IllegalMoveError struct {
+	Vertex string
+	Err    error
+}
+
+func (e IllegalMoveError) Error() string {
+	return fmt.Sprintf("gtp: engine played illegal move %s: %s", e.Vertex, e.Err.Error())
+}
+
+// Play seats the engine in the client's game and plays until the game is over,
+// or ctx ends while it waits for the opponent
+func Play(ctx context.Context, c *client.Client, e *Engine) error {
+	size := c.Size()
+	if err := setup(ctx, c, e); err != nil {
+		return err
+	}
+	for {
+		if c.CurrentPlayer() != c.Color() {
+			switch err := relay(ctx, c, e); err {
+			case nil:
+				continue
+			case game.ErrGameOver:
+				return nil
+			default:
+				return err
+			}
+		}
+		v, err := e.Command("genmove", Color(c.Color()))
+		if err != nil {
+			return err
+		}
+		if strings.ToLower(v) == "resign" {
+			if _, err := c.Resign(); err != nil {
+				return err
+			}
+			return ErrResigned
+		}
+		p, pass, err := ParseVertex(v, size)
+		if err != nil {
+			return err
+		}
+		if pass {
+			err = c.Pass()
+		} else {
+			err = c.Move(p)
+		}
+		switch err {
+		case nil:
+		case game.ErrGameOver:
+			return nil
+		default:
+			if _, ok := err.(game.MoveError); ok {
+				return IllegalMoveError{v, err}
+			}
+			return err
+		}
+	}
+}
+
+func setup(ctx context.Context, c *client.Client, e *Engine) error {
+	commands := [][]string{
+		{"boardsize", strconv.Itoa(c.Size())},
+		{"clear_board"},
+		{"komi", strconv.FormatFloat(c.Rules().Komi, 'f', -1, 64)},
+	}
+	for _, cmd := range commands {
+		if _, err := e.Command(cmd[0], cmd[1:]...); err != nil {
+			return err
+		}
+	}
+	return handicap(ctx, c, e)
+}
+
+// handicap tells the engine where Black's handicap stones are,
+// letting the engine choose them when it places free handicap stones for Black
+func handicap(ctx context.Context, c *client.Client, e *Engine) error {
+	h := c.Rules().Handicap
+	if h.Stones == 0 {
+		return nil
+	}
+	if h.Placement == game.FreePlacement {
+		if c.Color() == game.Black {
+			return placeHandicap(c, e, h.Stones)
+		}
+		if err := c.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	r, err := c.History()
+	if err != nil {
+		return err
+	}
+	var vertices []string
+	for _, m := range r.Setup {
+		if m.Player == game.Black {
+			vertices = append(vertices, Vertex(m.Position, c.Size()))
+		}
+	}
+	_, err = e.Command("set_free_handicap", vertices...)
+	return err
+}
+
+// placeHandicap asks the engine for n free handicap stones and plays them
+func placeHandicap(c *client.Client, e *Engine, n int) error {
+	v, err := e.Command("place_free_handicap", strconv.Itoa(n))
+	if err != nil {
+		return err
+	}
+	for _, v := range strings.Fields(v) {
+		p, _, err := ParseVertex(v, c.Size())
+		if err != nil {
+			return err
+		}
+		if err := c.Move(p); err != nil {
+			return IllegalMoveError{v, err}
+		}
+	}
+	return nil
+}
+
+// relay waits for the opponent and tells the engine what they played
+func relay(ctx context.Context, c *client.Client, e *Engine) error {
+	v, err := opponentMove(ctx, c)
+	if err != nil {
+		return err
+	}
+	_, err = e.Command("play", Color(c.Opponent()), v)
+	return err
+}
+
+// opponentMove waits for the opponent and returns the vertex they played, or pass
+func opponentMove(ctx context.Context, c *client.Client) (string, error) {
+	opponent := stones(c, c.Opponent())
+	if err := c.Wait(ctx); err != nil {
+		return "", err
+	}
+	if stones(c, c.Opponent()).Remaining < opponent.Remaining {
+		return Vertex(c.LastMove().Position, c.Size()), nil
+	}
+	return "pass", nil
+}
+
+func stones(c *client.Client, color game.Color) game.Stones {
+	if color == game.Black {
+		return c.BlackStones()
+	}
+	return c.WhiteStones()
+}
@@ -0,0 +1,116 @@
+// Package gtp connects Go Text Protocol engines to gobotgo games
+package gtp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// EngineError is a failure response ("? message") from an engine
+type EngineError struct {
+	Command string
+	Message string
+}
+
+func (e EngineError) Error() string {
+	return fmt.Sprintf("gtp: %s failed: %s", e.Command, e.Message)
+}
+
+// Engine sends commands to a GTP engine and reads its responses
+type Engine struct {
+	cmd     *exec.Cmd
+	in      io.WriteCloser
+	out     *bufio.Reader
+	waited  bool
+	waitErr error
+}
+
+// Start launches an engine as a subprocess speaking GTP on stdin and stdout.
+// The engine's stderr is passed through to ours.
+func Start(name string, args ...string) (*Engine, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	e := NewEngine(out, in)
+	e.cmd = cmd
+	return e, nil
+}
+
+// NewEngine talks GTP over an existing connection, reading responses from r
+func NewEngine(r io.Reader, w io.WriteCloser) *Engine {
+	return &Engine{
+		in:  w,
+		out: bufio.NewReader(r),
+	}
+}
+
+// Command sends a command and returns the text of a successful response
+func (e *Engine) Command(name string, args ...string) (string, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	if _, err := io.WriteString(e.in, line+"\n"); err != nil {
+		return "", e.exited(name, err)
+	}
+	var lines []string
+	for {
+		l, err := e.out.ReadString('\n')
+		if err != nil {
+			return "", e.exited(name, err)
+		}
+		l = strings.TrimRight(l, "\r\n")
+		if l == "" && len(lines) > 0 {
+			break
+		}
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	response := strings.Join(lines, "\n")
+	// Responses may carry the optional command id after the status
+	status, text := response[0], strings.TrimLeft(response[1:], "0123456789")
+	text = strings.TrimSpace(text)
+	switch status {
+	case '=':
+		return text, nil
+	case '?':
+		return "", EngineError{name, text}
+	default:
+		return "", fmt.Errorf("gtp: malformed response to %s: %q", name, response)
+	}
+}
+
+// exited reports a broken connection, including the exit status of a subprocess
+func (e *Engine) exited(name string, err error) error {
+	if werr := e.wait(); werr != nil {
+		err = werr
+	}
+	return fmt.Errorf("gtp: engine exited during %s: %s", name, err.Error())
+}
+
+func (e *Engine) wait() error {
+	if e.cmd != nil && !e.waited {
+		e.waited = true
+		e.waitErr = e.cmd.Wait()
+	}
+	return e.waitErr
+}
+
+// Close asks the engine to quit and waits for a subprocess to exit
+func (e *Engine) Close() error {
+	e.Command("quit")
+	e.in.Close()
+	return e.wait()
+}
@@ -0,0 +1,232 @@
+package gtp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/client"
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server"
+)
+
+func TestVertex(t *testing.T) {
+	tests := []struct {
+		size int
+		game.Position
+		vertex string
+	}{
+		{19, game.Position{X: 0, Y: 18}, "A1"},
+		{19, game.Position{X: 0, Y: 0}, "A19"},
+		{19, game.Position{X: 8, Y: 9}, "J10"},
+		{19, game.Position{X: 18, Y: 0}, "T19"},
+		{9, game.Position{X: 2, Y: 6}, "C3"},
+	}
+	for _, test := range tests {
+		if v := Vertex(test.Position, test.size); v != test.vertex {
+			t.Errorf("expected %v to be %s, got %s", test.Position, test.vertex, v)
+		}
+		p, pass, err := ParseVertex(strings.ToLower(test.vertex), test.size)
+		if err != nil || pass || p != test.Position {
+			t.Errorf("expected %s to parse to %v, got %v %v '%v'", test.vertex, test.Position, p, pass, err)
+		}
+	}
+	if _, pass, err := ParseVertex("PASS", 19); !pass || err != nil {
+		t.Errorf("expected pass, got %v '%v'", pass, err)
+	}
+	for _, v := range []string{"I5", "A0", "A20", "Z1", "", "5"} {
+		if _, _, err := ParseVertex(v, 19); err == nil {
+			t.Errorf("expected %q to be invalid", v)
+		}
+	}
+}
+
+// fakeEngine answers GTP commands, playing moves from a script then passing
+type fakeEngine struct {
+	moves    []string
+	commands []string
+}
+
+func (f *fakeEngine) start() *Engine {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		defer outW.Close()
+		s := bufio.NewScanner(inR)
+		for s.Scan() {
+			cmd := s.Text()
+			f.commands = append(f.commands, cmd)
+			response := "= "
+			switch {
+			case cmd == "crash":
+				return
+			case cmd == "unknown":
+				response = "? unknown command"
+			case strings.HasPrefix(cmd, "genmove"):
+				response += "pass"
+				if len(f.moves) > 0 {
+					response = "= " + f.moves[0]
+					f.moves = f.moves[1:]
+				}
+			}
+			io.WriteString(outW, response+"\n\n")
+		}
+	}()
+	return NewEngine(outR, inW)
+}
+
+func TestEngineErrors(t *testing.T) {
+	e := (&fakeEngine{}).start()
+	if _, err := e.Command("unknown"); err != (EngineError{"unknown", "unknown command"}) {
+		t.Errorf("expected engine error, got '%v'", err)
+	}
+	if _, err := e.Command("crash"); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("expected engine to have exited, got '%v'", err)
+	}
+}
+
+func play(c *client.Client, e *Engine) chan error {
+	done := make(chan error, 1)
+	go func() { done <- Play(context.Background(), c, e) }()
+	return done
+}
+
+func result(t *testing.T, done chan error) error {
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("game did not finish")
+		return nil
+	}
+}
+
+func TestPlay(t *testing.T) {
+	l := client.NewLocal()
+	b, err := client.Connect(l, server.DefaultSettings)
+	if err != nil {
+		t.Fatalf("unable to start black: '%s'", err)
+	}
+	w, err := client.Connect(l, server.DefaultSettings)
+	if err != nil {
+		t.Fatalf("unable to start white: '%s'", err)
+	}
+	black := &fakeEngine{moves: []string{"D4", "Q16"}}
+	white := &fakeEngine{moves: []string{"Q4", "D16"}}
+	bdone := play(b, black.start())
+	wdone := play(w, white.start())
+	if err := result(t, wdone); err != nil {
+		t.Errorf("unexpected error playing white: '%s'", err)
+	}
+	if err := result(t, bdone); err != nil {
+		t.Errorf("unexpected error playing black: '%s'", err)
+	}
+
+	expected := []string{
+		"boardsize 19", "clear_board", "komi 7.5",
+		"genmove black", "play white Q4",
+		"genmove black", "play white D16",
+		"genmove black",
+	}
+	if !reflect.DeepEqual(black.commands, expected) {
+		t.Errorf("unexpected black commands:\nexp: %v\ngot: %v", expected, black.commands)
+	}
+	expected = []string{
+		"boardsize 19", "clear_board", "komi 7.5",
+		"play black D4", "genmove white",
+		"play black Q16", "genmove white",
+		"play black pass", "genmove white",
+	}
+	if !reflect.DeepEqual(white.commands, expected) {
+		t.Errorf("unexpected white commands:\nexp: %v\ngot: %v", expected, white.commands)
+	}
+}
+
+func TestPlayIllegal(t *testing.T) {
+	l := client.NewLocal()
+	b, err := client.Connect(l, server.DefaultSettings)
+	if err != nil {
+		t.Fatalf("unable to start black: '%s'", err)
+	}
+	w, err := client.Connect(l, server.DefaultSettings)
+	if err != nil {
+		t.Fatalf("unable to start white: '%s'", err)
+	}
+	play(b, (&fakeEngine{moves: []string{"D4"}}).start())
+	err = result(t, play(w, (&fakeEngine{moves: []string{"D4"}}).start()))
+	if e, ok := err.(IllegalMoveError); !ok || e.Err != game.ErrSpotNotEmpty {
+		t.Errorf("expected illegal move error, got '%v'", err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	ts := httptest.NewServer(server.MuxerAPI(server.NewMemoryStore()))
+	b, err := client.New(ts.URL)
+	if err != nil {
+		t.Fatalf("unable to start black: '%s'", err)
+	}
+	w, err := client.New(ts.URL)
+	if err != nil {
+		t.Fatalf("unable to start white: '%s'", err)
+	}
+	// White only plays when the controller asks for its move
+	turns := make(chan bool, 2)
+	go func() {
+		<-turns
+		w.Wait(context.Background())
+		w.Move(game.Position{X: 15, Y: 3})
+		<-turns
+		w.Wait(context.Background())
+		w.Pass()
+	}()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- Serve(b, inR, outW) }()
+	controller := NewEngine(outR, inW)
+
+	tests := []struct {
+		command  string
+		response string
+		err      bool
+	}{
+		{"protocol_version", "2", false},
+		{"known_command genmove", "true", false},
+		{"boardsize 9", "", true},
+		{"boardsize 19", "", false},
+		{"play white D4", "", true},
+		{"genmove black", "", true},
+		{"play black D4", "", false},
+		{"play black D5", "", true},
+		{"genmove white", "Q16", false},
+		{"play black D10", "", false},
+		{"genmove white", "pass", false},
+		// Passing after White's pass ends the game
+		{"play black pass", "", false},
+		{"play black D11", "", true},
+		{"final_score", "W+6.5", false},
+		{"unknown_command", "", true},
+	}
+	for _, test := range tests {
+		fields := strings.Fields(test.command)
+		if test.command == "genmove white" {
+			turns <- true
+		}
+		response, err := controller.Command(fields[0], fields[1:]...)
+		if (err != nil) != test.err || response != test.response {
+			t.Errorf("%s: expected %q error %v, got %q '%v'", test.command, test.response, test.err, response, err)
+		}
+	}
+	if _, err := controller.Command("quit"); err != nil {
+		t.Errorf("unexpected error quitting, got '%s'", err)
+	}
+	if err := result(t, done); err != nil {
+		t.Errorf("unexpected error serving, got '%s'", err)
+	}
+}
@@ -0,0 +1,179 @@
+package gtp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/client"
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/game/sgf"
+)
+
+// Version is reported to GTP controllers by Serve
+const Version = "1.0"
+
+var commands = []string{
+	"protocol_version", "name", "version", "known_command", "list_commands", "quit",
+	"boardsize", "clear_board", "komi", "play", "genmove", "showboard", "final_score",
+}
+
+// Serve presents the client's game as a GTP engine, reading commands from r and writing responses to w.
+// The controller plays the client's seat with play, and genmove for the opponent waits for the
+// player seated on the server. Serve returns when the controller quits or r is closed.
+func Serve(c *client.Client, r io.Reader, w io.Writer) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		// Comments and blank lines are ignored
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		id := ""
+		if _, err := strconv.Atoi(fields[0]); err == nil {
+			id, fields = fields[0], fields[1:]
+			if len(fields) == 0 {
+				continue
+			}
+		}
+		response, err := command(c, fields[0], fields[1:])
+		status := "="
+		if err != nil {
+			status, response = "?", err.Error()
+		}
+		if response != "" {
+			response = " " + response
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s\n\n", status, id, response); err != nil {
+			return err
+		}
+		if fields[0] == "quit" {
+			return nil
+		}
+	}
+	return s.Err()
+}
+
+func command(c *client.Client, name string, args []string) (string, error) {
+	switch name {
+	case "protocol_version":
+		return "2", nil
+	case "name":
+		return "gobotgo", nil
+	case "version":
+		return Version, nil
+	case "known_command":
+		if len(args) == 1 {
+			for _, cmd := range commands {
+				if cmd == args[0] {
+					return "true", nil
+				}
+			}
+		}
+		return "false", nil
+	case "list_commands":
+		return strings.Join(commands, "\n"), nil
+	case "quit":
+		return "", nil
+	case "boardsize":
+		if len(args) != 1 || args[0] != strconv.Itoa(c.Size()) {
+			return "", fmt.Errorf("unacceptable size")
+		}
+		return "", nil
+	case "clear_board", "komi":
+		// The game is set up by the server
+		return "", nil
+	case "play":
+		return "", playMove(c, args)
+	case "genmove":
+		return genmove(c, args)
+	case "showboard":
+		return "\n" + showboard(c), nil
+	case "final_score":
+		return sgf.Result(c.Score()), nil
+	default:
+		return "", fmt.Errorf("unknown command")
+	}
+}
+
+// playMove forwards the controller's move for the client's seat
+func playMove(c *client.Client, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("syntax error")
+	}
+	color, err := ParseColor(args[0])
+	if err != nil {
+		return fmt.Errorf("syntax error")
+	}
+	if color != c.Color() {
+		return fmt.Errorf("%s is played by the server", Color(color))
+	}
+	p, pass, err := ParseVertex(args[1], c.Size())
+	if err != nil {
+		return fmt.Errorf("syntax error")
+	}
+	if pass {
+		err = c.Pass()
+		if err == game.ErrGameOver {
+			// The controller's pass after the opponent's ended the game
+			return nil
+		}
+	} else {
+		err = c.Move(p)
+	}
+	if err != nil {
+		return fmt.Errorf("illegal move: %s", err.Error())
+	}
+	return nil
+}
+
+// genmove waits for the opponent seated on the server
+func genmove(c *client.Client, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("syntax error")
+	}
+	color, err := ParseColor(args[0])
+	if err != nil {
+		return "", fmt.Errorf("syntax error")
+	}
+	if color != c.Opponent() {
+		return "", fmt.Errorf("%s is played by the controller", Color(color))
+	}
+	if c.CurrentPlayer() != c.Opponent() {
+		return "", fmt.Errorf("it is not %s's turn", Color(color))
+	}
+	v, err := opponentMove(context.Background(), c)
+	if err == game.ErrGameOver {
+		if r, _ := c.Result(); r.Reason == game.ResignReason && r.Winner == c.Color() {
+			return "resign", nil
+		}
+		return "pass", nil
+	}
+	return v, err
+}
+
+func showboard(c *client.Client) string {
+	b := c.State()
+	size := len(b)
+	var lines []string
+	header := "  "
+	for x := 0; x < size; x++ {
+		header += " " + string(columns[x])
+	}
+	lines = append(lines, header)
+	for y := 0; y < size; y++ {
+		row := fmt.Sprintf("%2d", size-y)
+		for x := 0; x < size; x++ {
+			row += " " + b[x][y].Dot()
+		}
+		lines = append(lines, row)
+	}
+	return strings.Join(lines, "\n")
+}
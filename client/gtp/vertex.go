@@ -0,0 +1,52 @@
+package gtp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// Columns skip I to avoid confusion with J
+const columns = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+
+// Vertex formats a position as a GTP vertex such as "D4".
+// GTP counts rows from the bottom of the board, positions count Y from the top.
+func Vertex(p game.Position, size int) string {
+	return fmt.Sprintf("%c%d", columns[p.X], size-p.Y)
+}
+
+// ParseVertex reads a GTP vertex, reporting pass for "pass"
+func ParseVertex(v string, size int) (p game.Position, pass bool, err error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if v == "PASS" {
+		return game.Position{}, true, nil
+	}
+	if len(v) < 2 {
+		return game.Position{}, false, fmt.Errorf("gtp: invalid vertex %q", v)
+	}
+	x := strings.IndexByte(columns, v[0])
+	row, err := strconv.Atoi(v[1:])
+	if x < 0 || x >= size || err != nil || row < 1 || row > size {
+		return game.Position{}, false, fmt.Errorf("gtp: invalid vertex %q", v)
+	}
+	return game.Position{X: x, Y: size - row}, false, nil
+}
+
+// Color formats a color as a GTP color
+func Color(c game.Color) string {
+	return strings.ToLower(c.String())
+}
+
+// ParseColor reads a GTP color, "b", "black", "w" or "white"
+func ParseColor(s string) (game.Color, error) {
+	switch strings.ToLower(s) {
+	case "b", "black":
+		return game.Black, nil
+	case "w", "white":
+		return game.White, nil
+	default:
+		return game.None, fmt.Errorf("gtp: invalid color %q", s)
+	}
+}
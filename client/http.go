@@ -0,0 +1,195 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server"
+)
+
+// root is the version of the API the client speaks
+const root = "/api/v2/game/"
+
+// HTTP is the Transport to a gobotgo server
+type HTTP struct {
+	client http.Client
+	url    string
+	// key is the API key of the registered player, sent with every request
+	key string
+}
+
+// NewHTTP plays on the server at url, as the registered player with the API key if it is set
+func NewHTTP(url, key string) *HTTP {
+	return &HTTP{
+		client: http.Client{Timeout: time.Minute * 10},
+		url:    url,
+		key:    key,
+	}
+}
+
+func (h *HTTP) playURL(id server.GameID, s string) string {
+	return fmt.Sprintf("%s%splay/%s/%s", h.url, root, id, s)
+}
+
+// do sends a request with the player's API key, if they have one
+func (h *HTTP) do(method, url string, body io.Reader) (*http.Response, error) {
+	return h.doContext(context.Background(), method, url, body)
+}
+
+func (h *HTTP) doContext(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if h.key != "" {
+		r.Header.Set(server.KeyHeader, h.key)
+	}
+	return h.client.Do(r)
+}
+
+// send makes a request and decodes the response into v
+func (h *HTTP) send(method, url string, body io.Reader, v interface{}) error {
+	resp, err := h.do(method, url, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, v)
+}
+
+// decode reads a successful response into v. The server.Error of a failed response
+// is returned as the game.MoveError with the same code, if there is one.
+func decode(resp *http.Response, v interface{}) error {
+	if resp.StatusCode == http.StatusOK {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+	var e server.Error
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return fmt.Errorf("Bad response %s: %s", resp.Status, err.Error())
+	}
+	if err := e.Unwrap(); err != nil {
+		return err
+	}
+	return e
+}
+
+func (h *HTTP) Start(s server.Settings) (server.GameID, game.Color, error) {
+	v := struct {
+		ID    server.GameID
+		Color game.Color
+	}{}
+	err := h.send("GET", h.url+root+"start/?"+s.Query().Encode(), nil, &v)
+	return v.ID, v.Color, err
+}
+
+// lobby sends a lobby request that seats the player, such as challenge or join/<game>
+func (h *HTTP) lobby(action string, v neturl.Values) (server.GameID, game.Color, error) {
+	s := struct {
+		ID    server.GameID
+		Color game.Color
+	}{}
+	err := h.send("GET", h.url+root+"lobby/"+action+"?"+v.Encode(), nil, &s)
+	return s.ID, s.Color, err
+}
+
+func (h *HTTP) State(id server.GameID) (State, error) {
+	var s State
+	err := h.send("GET", h.playURL(id, "state"), nil, &s)
+	return s, err
+}
+
+func (h *HTTP) move(id server.GameID, m []int) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var response string
+	return h.send("POST", h.playURL(id, "move"), bytes.NewBuffer(data), &response)
+}
+
+func (h *HTTP) Move(id server.GameID, p game.Position) error {
+	return h.move(id, []int{p.X, p.Y})
+}
+
+func (h *HTTP) Pass(id server.GameID) error {
+	return h.move(id, []int{})
+}
+
+func (h *HTTP) Resign(id server.GameID) (game.Result, error) {
+	var result game.Result
+	err := h.send("POST", h.playURL(id, "resign"), nil, &result)
+	return result, err
+}
+
+func (h *HTTP) Undo(id server.GameID, answer string) error {
+	u := h.playURL(id, "undo")
+	if answer != "" {
+		u += "?answer=" + answer
+	}
+	var response string
+	return h.send("POST", u, nil, &response)
+}
+
+// waitTimeout is how long the server holds each wait request, well within the client's timeout
+const waitTimeout = "1m"
+
+// Wait asks again each time the server gives up waiting
+func (h *HTTP) Wait(ctx context.Context, id server.GameID) error {
+	for {
+		resp, err := h.doContext(ctx, "GET", h.playURL(id, "wait?timeout="+waitTimeout), nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if resp.StatusCode == http.StatusNoContent {
+			resp.Body.Close()
+			continue
+		}
+		var response string
+		err = decode(resp, &response)
+		resp.Body.Close()
+		return err
+	}
+}
+
+func (h *HTTP) History(id server.GameID) (game.Record, error) {
+	var r game.Record
+	err := h.send("GET", h.playURL(id, "history"), nil, &r)
+	return r, err
+}
+
+// Register registers a player or bot at url, and returns them with the API key
+// NewPlayer needs to play as them. The key cannot be retrieved again.
+func Register(url string, p server.Player) (server.Player, string, error) {
+	v := neturl.Values{}
+	v.Set("name", p.Name)
+	v.Set("kind", p.Kind.String())
+	v.Set("owner", p.Owner)
+	v.Set("language", p.Language)
+	v.Set("version", p.Version)
+	resp, err := http.PostForm(url+"/api/v2/players/register", v)
+	if err != nil {
+		return p, "", err
+	}
+	defer resp.Body.Close()
+	var r struct {
+		Player server.Player
+		Key    string
+	}
+	if err := decode(resp, &r); err != nil {
+		return p, "", err
+	}
+	return r.Player, r.Key, nil
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server"
+)
+
+// Local is a Transport that plays a single game in memory, so bots can be tested
+// without a server. It follows the server's rules for turns, undo and resignation,
+// but games are untimed. The first player to start takes Black, and the second,
+// who must ask for the same settings, takes White.
+type Local struct {
+	mu       sync.Mutex
+	settings server.Settings
+	state    *game.State
+	seats    map[server.GameID]game.Color
+	undo     game.Color
+	// changed is closed and replaced after every turn, waking players who wait
+	changed chan struct{}
+}
+
+// NewLocal creates an empty game for two clients to Connect to
+func NewLocal() *Local {
+	return &Local{
+		seats:   map[server.GameID]game.Color{},
+		changed: make(chan struct{}),
+	}
+}
+
+// Start seats players as Black then White. GameIDs are the player's color.
+func (l *Local) Start(s server.Settings) (server.GameID, game.Color, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := game.Black
+	switch len(l.seats) {
+	case 0:
+		// The server refuses settings it cannot play with the same error
+		if err := s.Check(); err != nil {
+			return "", game.None, server.Error{Code: server.CodeBadRules, Message: err.Error()}
+		}
+		l.settings, l.state = s, game.MustNew(s.Size, server.Pieces, s.Rules)
+	case 1:
+		if s != l.settings {
+			return "", game.None, server.Error{Code: server.CodeBadRules, Message: "The local game is played with other settings"}
+		}
+		c = game.White
+	default:
+		return "", game.None, server.Error{Code: server.CodeBadRequest, Message: "The local game already has two players"}
+	}
+	id := server.GameID(c.String())
+	l.seats[id] = c
+	return id, c, nil
+}
+
+// color finds the player's seat, and must be called with l.mu held
+func (l *Local) color(id server.GameID) (game.Color, error) {
+	c, ok := l.seats[id]
+	if !ok {
+		return game.None, server.Error{Code: server.CodeUnknownID, Message: "No player for id " + string(id)}
+	}
+	return c, nil
+}
+
+// changes wakes the players waiting for their turn, and must be called with l.mu held
+func (l *Local) changes() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *Local) State(id server.GameID) (State, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.color(id); err != nil {
+		return State{}, err
+	}
+	s := State{PublicState: l.state.Public(), UndoRequest: l.undo}
+	s.Board = s.Board.Copy()
+	return s, nil
+}
+
+func (l *Local) Move(id server.GameID, p game.Position) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, err := l.color(id)
+	if err != nil {
+		return err
+	}
+	played := len(l.state.History())
+	err = l.state.Move(game.Move{Player: c, Position: p})
+	if len(l.state.History()) > played {
+		l.undo = game.None
+	}
+	l.changes()
+	return err
+}
+
+func (l *Local) Pass(id server.GameID) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, err := l.color(id)
+	if err != nil {
+		return err
+	}
+	played := len(l.state.History())
+	err = l.state.Pass(c)
+	if len(l.state.History()) > played {
+		l.undo = game.None
+	}
+	l.changes()
+	return err
+}
+
+func (l *Local) Resign(id server.GameID) (game.Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, err := l.color(id)
+	if err != nil {
+		return game.Result{}, err
+	}
+	if err := l.state.Resign(c); err != nil {
+		return game.Result{}, err
+	}
+	l.undo = game.None
+	l.changes()
+	result, _ := l.state.Result()
+	return result, nil
+}
+
+func (l *Local) Undo(id server.GameID, answer string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, err := l.color(id)
+	if err != nil {
+		return err
+	}
+	switch {
+	case l.state.Over():
+		return game.ErrGameOver
+	case answer == "":
+		if len(l.state.History()) == 0 {
+			return game.ErrNoUndo
+		}
+		l.undo = c
+		return nil
+	case l.undo != c.Opponent():
+		return server.Error{Code: server.CodeNoUndoRequest, Message: "No undo requested by opponent"}
+	case answer == "reject":
+		l.undo = game.None
+		return nil
+	case answer != "accept":
+		return server.Error{Code: server.CodeBadRequest, Message: answer + " is not a valid undo answer"}
+	}
+	if err := l.state.Undo(); err != nil {
+		return err
+	}
+	l.undo = game.None
+	l.changes()
+	return nil
+}
+
+// Wait blocks until it is the player's turn, and both players stop waiting once the game is over
+func (l *Local) Wait(ctx context.Context, id server.GameID) error {
+	l.mu.Lock()
+	c, err := l.color(id)
+	for err == nil {
+		if l.state.Over() {
+			err = game.ErrGameOver
+			break
+		}
+		if l.state.Public().CurrentPlayer == c {
+			break
+		}
+		changed := l.changed
+		l.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	l.mu.Unlock()
+	return err
+}
+
+func (l *Local) History(id server.GameID) (game.Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.color(id); err != nil {
+		return game.Record{}, err
+	}
+	return l.state.Record(), nil
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -11,7 +12,9 @@ import (
 type validate struct {
 	ch    chan int
 	count int
-	t     *testing.T
+	// running are the functions scheduled, which Verify waits to return
+	running sync.WaitGroup
+	t       *testing.T
 }
 
 func validator(t *testing.T) *validate {
@@ -36,6 +39,7 @@ func (v *validate) Verify(d time.Duration) {
 			count++
 			if count == v.count {
 				v.count = 0
+				v.wait(timeout)
 				return
 			}
 		case <-timeout:
@@ -46,9 +50,25 @@ func (v *validate) Verify(d time.Duration) {
 	}
 }
 
+// wait blocks until the scheduled functions have returned, so the next ones do not run alongside them
+func (v *validate) wait(timeout <-chan time.Time) {
+	done := make(chan struct{})
+	go func() {
+		v.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-timeout:
+		v.t.Fatal("verify timed out waiting for events to finish")
+	}
+}
+
 func (v *validate) Before(order int, f func()) {
 	v.count++
+	v.running.Add(1)
 	go func() {
+		defer v.running.Done()
 		v.ch <- order
 		f()
 	}()
@@ -56,7 +76,9 @@ func (v *validate) Before(order int, f func()) {
 
 func (v *validate) After(order int, f func()) {
 	v.count++
+	v.running.Add(1)
 	go func() {
+		defer v.running.Done()
 		f()
 		v.ch <- order
 	}()
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server"
+)
+
+// Transport carries a player's requests to their game. HTTP plays on a gobotgo
+// server and Local plays in memory, and both answer with the same errors:
+// game.MoveError values such as game.ErrWrongPlayer and game.ErrGameOver, or a server.Error.
+type Transport interface {
+	// Start seats the player in a game with the settings, returning their GameID and color
+	Start(s server.Settings) (server.GameID, game.Color, error)
+	State(id server.GameID) (State, error)
+	Move(id server.GameID, p game.Position) error
+	Pass(id server.GameID) error
+	// Resign ends the game at any time, returning the result
+	Resign(id server.GameID) (game.Result, error)
+	// Undo asks to take back the last turn when answer is empty,
+	// or answers the opponent's request with "accept" or "reject"
+	Undo(id server.GameID, answer string) error
+	// Wait returns once it is the player's turn, game.ErrGameOver once the game has ended,
+	// or the context's error if it ends first
+	Wait(ctx context.Context, id server.GameID) error
+	History(id server.GameID) (game.Record, error)
+}
+
+// State is the game state as seen by a player
+type State struct {
+	// Game is the number spectators watch the game by, which Local games do not have
+	Game uint64 `json:"game"`
+	game.PublicState
+	UndoRequest game.Color `json:"undorequest"`
+	// Clocks is nil for untimed games
+	Clocks *server.Clocks `json:"clocks"`
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -38,24 +39,10 @@ func main() {
 	printWinner(c)
 }
 
-func score(c *client.Client) (black, white int) {
-	black, white = c.State().Score()
-	black += c.BlackStones().Captured
-	white += c.WhiteStones().Captured
-	return
-}
-
 func printWinner(c *client.Client) {
-	winner := game.None
-	black, white := score(c)
-	switch {
-	case black > white:
-		winner = game.Black
-	case black < white:
-		winner = game.White
-	}
-	log.Printf("Score: b: %d, w: %d", black, white)
-	log.Println("Game won by", winner)
+	score := c.Score()
+	log.Printf("Score: b: %.1f, w: %.1f", score.Black.Total, score.White.Total)
+	log.Println("Game won by", score.Winner)
 }
 
 type decision int
@@ -74,7 +61,7 @@ func play(c *client.Client) error {
 			case game.ErrSpotNotEmpty:
 				fallthrough
 			case game.ErrSelfCapture:
-				log.Printf("(%d): invalid move %+v: '%s'", act.ID(), act.Position, err.Error())
+				log.Printf("(%s): invalid move %+v: '%s'", act.ID(), act.Position, err.Error())
 				// Make sure we update the state after this
 				act.choice = wait
 
@@ -100,7 +87,7 @@ type action struct {
 func (act *action) Act() error {
 	switch act.choice {
 	case wait:
-		return act.Wait()
+		return act.Wait(context.Background())
 	case pass:
 		return act.Pass()
 	case move:
@@ -0,0 +1,168 @@
+// gobotgo-match plays two GTP engines against each other and reports the
+// first engine's win rate and Elo difference with 95% confidence intervals.
+//
+//	gobotgo-match -games 200 -parallel 4 -size 9 -sgf games "gnugo --mode gtp --level 1" "gnugo --mode gtp --level 10"
+//
+// The engines alternate colors, with the first engine taking Black in the first game.
+// Games are played on an in-process server unless -url gives a running one.
+// The bots are registered on the server and each game is a challenge between them,
+// so no other player can take their seats.
+//
+// With -sprt the match stops early once a sequential probability ratio test accepts
+// that the first engine is -elo0 (H0) or -elo1 (H1) points stronger.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server"
+)
+
+var url = flag.String("url", "", "Root URL of a running gobotgo service, an in-process server is used if unset")
+var games = flag.Int("games", 100, "Number of games to play")
+var parallel = flag.Int("parallel", 1, "Number of games to play at once")
+var size = flag.Int("size", server.DefaultSettings.Size, "Board size")
+var komi = flag.Float64("komi", server.DefaultSettings.Rules.Komi, "Komi")
+var sgfDir = flag.String("sgf", "", "Directory to write an SGF file of each game to")
+var useSPRT = flag.Bool("sprt", false, "Stop once a sequential probability ratio test is decided")
+var elo0 = flag.Float64("elo0", 0, "Elo difference of the SPRT null hypothesis")
+var elo1 = flag.Float64("elo1", 10, "Elo difference of the SPRT alternative hypothesis")
+var alpha = flag.Float64("alpha", 0.05, "SPRT probability of accepting H1 when H0 is true")
+var beta = flag.Float64("beta", 0.05, "SPRT probability of accepting H0 when H1 is true")
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 2 {
+		log.Fatal(`usage: gobotgo-match [flags] "engine [args...]" "engine [args...]"`)
+	}
+	bots, err := parseBots(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *url == "" {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log.Fatal(err)
+		}
+		go http.Serve(l, server.MuxerAPI(server.NewMemoryStore()))
+		*url = "http://" + l.Addr().String()
+	}
+	if *sgfDir != "" {
+		if err := os.MkdirAll(*sgfDir, 0755); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := register(*url, bots); err != nil {
+		log.Fatal(err)
+	}
+	m := &match{url: *url, settings: server.DefaultSettings, bots: bots}
+	m.settings.Size = *size
+	m.settings.Rules.Komi = *komi
+	test := sprt{*elo0, *elo1, *alpha, *beta}
+
+	jobs := make(chan int)
+	stop := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for n := 0; n < *games; n++ {
+			select {
+			case jobs <- n:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	outcomes := make(chan outcome)
+	var wg sync.WaitGroup
+	for i := 0; i < *parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				outcomes <- m.play(n)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	var r results
+	decision := 0
+	for o := range outcomes {
+		if o.err != nil {
+			log.Printf("game %d: %s", o.n+1, o.err.Error())
+		}
+		// Games that never started are not counted
+		if o.record == nil {
+			continue
+		}
+		r.add(o.result, o.color)
+		log.Printf("game %d: %s (%s) vs %s (%s): %s", o.n+1, o.record.Black, game.Black, o.record.White, game.White, o.record.Result)
+		if *sgfDir != "" {
+			if err := writeSGF(o); err != nil {
+				log.Println(err)
+			}
+		}
+		if *useSPRT && decision == 0 {
+			if decision = test.decide(r); decision != 0 {
+				close(stop)
+			}
+		}
+	}
+	report(os.Stdout, bots, r)
+	if *useSPRT {
+		lower, upper := test.bounds()
+		verdict := "undecided"
+		switch decision {
+		case -1:
+			verdict = fmt.Sprintf("H0 accepted, %s is %g Elo stronger", bots[0].name, *elo0)
+		case 1:
+			verdict = fmt.Sprintf("H1 accepted, %s is %g Elo stronger", bots[0].name, *elo1)
+		}
+		fmt.Printf("SPRT [%g, %g]: LLR %.2f (%.2f, %.2f), %s\n", *elo0, *elo1, test.llr(r), lower, upper, verdict)
+	}
+}
+
+func writeSGF(o outcome) error {
+	f, err := os.Create(filepath.Join(*sgfDir, fmt.Sprintf("game-%03d.sgf", o.n+1)))
+	if err != nil {
+		return err
+	}
+	if err := o.record.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// report prints a table of each bot's results, then the first bot's score and Elo difference
+func report(w io.Writer, bots []bot, r results) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "bot\tgames\twins\tdraws\tlosses\twins as Black\twins as White\t")
+	blackWins, whiteWins := r.black[0], r.wins-r.black[0]
+	// The second bot wins the games the first loses, as the other color
+	blackLosses := r.black[2]
+	whiteLosses := r.losses - blackLosses
+	fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t\n", bots[0].name, r.games(), r.wins, r.draws, r.losses, blackWins, whiteWins)
+	fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t\n", bots[1].name, r.games(), r.losses, r.draws, r.wins, whiteLosses, blackLosses)
+	tw.Flush()
+	if r.games() == 0 {
+		return
+	}
+	mean, _ := r.score()
+	low, high := r.interval()
+	fmt.Fprintf(w, "%s scores %.1f%% [%.1f%%, %.1f%%], Elo %+.0f [%+.0f, %+.0f]\n",
+		bots[0].name, 100*mean, 100*low, 100*high, elo(mean), elo(low), elo(high))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/client"
+	"github.com/gophergala2016/gobotgo/client/gtp"
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/game/sgf"
+	"github.com/gophergala2016/gobotgo/server"
+)
+
+// bot is a GTP engine command line
+type bot struct {
+	name    string
+	command []string
+	// player is the name the bot is registered with on the server, and key its API key
+	player, key string
+}
+
+// parseBots splits the bots' command lines, naming each after its program
+func parseBots(commands []string) ([]bot, error) {
+	var bots []bot
+	for _, c := range commands {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty bot command")
+		}
+		bots = append(bots, bot{name: filepath.Base(fields[0]), command: fields})
+	}
+	if len(bots) == 2 && bots[0].name == bots[1].name {
+		bots[0].name += " #1"
+		bots[1].name += " #2"
+	}
+	return bots, nil
+}
+
+// register registers the bots at url under names of their own, so each game can be
+// a challenge that no other player is seated in
+func register(url string, bots []bot) error {
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	for i := range bots {
+		p, key, err := client.Register(url, server.Player{Name: bots[i].name + " " + suffix, Kind: server.Bot})
+		if err != nil {
+			return fmt.Errorf("%s: %s", bots[i].name, err.Error())
+		}
+		bots[i].player, bots[i].key = p.Name, key
+	}
+	return nil
+}
+
+// match plays games between two bots on a server
+type match struct {
+	url      string
+	settings server.Settings
+	bots     []bot
+}
+
+// outcome is how a game of the match ended
+type outcome struct {
+	n int
+	// color is the color the first bot played
+	color  game.Color
+	result game.Result
+	record *sgf.Record
+	// err is the failure of a bot, which loses the game
+	err error
+}
+
+// play plays game n of the match, with the first bot taking Black in even games
+func (m *match) play(n int) outcome {
+	o := outcome{n: n, color: game.Black}
+	black, white := m.bots[0], m.bots[1]
+	if n%2 == 1 {
+		o.color = game.White
+		black, white = white, black
+	}
+	var engines []*gtp.Engine
+	defer func() {
+		for _, e := range engines {
+			e.Close()
+		}
+	}()
+	for _, b := range []bot{black, white} {
+		e, err := gtp.Start(b.command[0], b.command[1:]...)
+		if err != nil {
+			o.err = fmt.Errorf("%s: %s", b.name, err.Error())
+			return o
+		}
+		engines = append(engines, e)
+	}
+
+	// Black challenges White, so neither seat can be taken by anyone else on the server
+	bc, err := client.Challenge(m.url, black.key, white.player, m.settings)
+	if err != nil {
+		o.err = err
+		return o
+	}
+	wc, err := client.Join(m.url, white.key, bc.Game())
+	if err != nil {
+		bc.Resign()
+		o.err = err
+		return o
+	}
+	clients := []*client.Client{bc, wc}
+
+	errs := make(chan error, 2)
+	for i, b := range []bot{black, white} {
+		go func(b bot, c *client.Client, e *gtp.Engine) {
+			err := gtp.Play(context.Background(), c, e)
+			if err == nil || err == gtp.ErrResigned {
+				errs <- nil
+				return
+			}
+			// A bot that fails forfeits, which ends the game for its opponent
+			c.Resign()
+			errs <- fmt.Errorf("%s: %s", b.name, err.Error())
+		}(b, clients[i], engines[i])
+	}
+	for range clients {
+		if err := <-errs; err != nil && o.err == nil {
+			o.err = err
+		}
+	}
+
+	rec, err := clients[0].History()
+	if err != nil {
+		o.err = err
+		return o
+	}
+	s, err := game.Replay(rec, len(rec.Turns))
+	if err != nil {
+		o.err = err
+		return o
+	}
+	o.result, _ = s.Result()
+	o.record = sgf.FromState(s)
+	o.record.Black, o.record.White = black.name, white.name
+	o.record.Comment = fmt.Sprintf("Game %d of %s vs %s", n+1, m.bots[0].name, m.bots[1].name)
+	return o
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// results counts the first bot's wins, draws and losses
+type results struct {
+	wins, draws, losses int
+	// black counts the wins, draws and losses of the first bot playing Black
+	black [3]int
+}
+
+// add counts a game the first bot played as color
+func (r *results) add(result game.Result, color game.Color) {
+	i := 1
+	switch result.Winner {
+	case color:
+		i = 0
+		r.wins++
+	case game.None:
+		r.draws++
+	default:
+		i = 2
+		r.losses++
+	}
+	if color == game.Black {
+		r.black[i]++
+	}
+}
+
+func (r results) games() int {
+	return r.wins + r.draws + r.losses
+}
+
+// score is the first bot's mean score, a win being 1 and a draw 0.5,
+// and the variance of the score of a single game
+func (r results) score() (mean, variance float64) {
+	return moments(float64(r.wins), float64(r.draws), float64(r.losses))
+}
+
+func moments(wins, draws, losses float64) (mean, variance float64) {
+	n := wins + draws + losses
+	if n == 0 {
+		return 0.5, 0
+	}
+	mean = (wins + draws/2) / n
+	variance = (wins*math.Pow(1-mean, 2) + draws*math.Pow(0.5-mean, 2) + losses*math.Pow(mean, 2)) / n
+	return mean, variance
+}
+
+// interval is the 95% confidence interval of the mean score
+func (r results) interval() (low, high float64) {
+	mean, variance := r.score()
+	if r.games() == 0 {
+		return 0, 1
+	}
+	margin := 1.96 * math.Sqrt(variance/float64(r.games()))
+	return math.Max(mean-margin, 0), math.Min(mean+margin, 1)
+}
+
+// elo is the rating difference that gives a mean score of score
+func elo(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
+
+// expected is the mean score of a player rated elo points higher than their opponent
+func expected(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// sprt is a sequential probability ratio test of whether the first bot is elo0 (H0)
+// or elo1 (H1) points stronger, stopping when either is accepted with the error
+// rates alpha and beta
+type sprt struct {
+	elo0, elo1  float64
+	alpha, beta float64
+}
+
+// llr is the log likelihood ratio of H1 to H0, approximating the score as normally
+// distributed. Half a game of each result is added, so a short run of wins alone
+// does not end the test.
+func (s sprt) llr(r results) float64 {
+	wins, draws, losses := float64(r.wins)+0.5, float64(r.draws)+0.5, float64(r.losses)+0.5
+	mean, variance := moments(wins, draws, losses)
+	s0, s1 := expected(s.elo0), expected(s.elo1)
+	n := wins + draws + losses
+	return n * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// bounds are the log likelihood ratios at which H0 and H1 are accepted
+func (s sprt) bounds() (lower, upper float64) {
+	return math.Log(s.beta / (1 - s.alpha)), math.Log((1 - s.beta) / s.alpha)
+}
+
+// decide returns 0 while the test continues, -1 once H0 is accepted and 1 for H1
+func (s sprt) decide(r results) int {
+	llr := s.llr(r)
+	lower, upper := s.bounds()
+	switch {
+	case llr <= lower:
+		return -1
+	case llr >= upper:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+func TestResults(t *testing.T) {
+	var r results
+	r.add(game.Result{Winner: game.Black}, game.Black)
+	r.add(game.Result{Winner: game.Black}, game.White)
+	r.add(game.Result{Winner: game.None}, game.White)
+	r.add(game.Result{Winner: game.White}, game.White)
+	if r.wins != 2 || r.draws != 1 || r.losses != 1 || r.black != [3]int{1, 0, 0} {
+		t.Errorf("unexpected results %+v", r)
+	}
+	mean, variance := r.score()
+	if mean != 0.625 || math.Abs(variance-0.171875) > 1e-9 {
+		t.Errorf("expected score 0.625 with variance 0.171875, got %g %g", mean, variance)
+	}
+	if low, high := r.interval(); low <= 0.2 || high != 1 {
+		t.Errorf("unexpected interval %g-%g", low, high)
+	}
+}
+
+func TestElo(t *testing.T) {
+	tests := []struct {
+		score, elo float64
+	}{
+		{0.5, 0},
+		{0.75, 190.849},
+		{0.25, -190.849},
+		{0.9, 381.697},
+	}
+	for _, test := range tests {
+		if e := elo(test.score); math.Abs(e-test.elo) > 1e-3 {
+			t.Errorf("score %g: expected %g Elo, got %g", test.score, test.elo, e)
+		}
+		if s := expected(test.elo); math.Abs(s-test.score) > 1e-6 {
+			t.Errorf("%g Elo: expected score %g, got %g", test.elo, test.score, s)
+		}
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	s := sprt{elo0: 0, elo1: 50, alpha: 0.05, beta: 0.05}
+	if lower, upper := s.bounds(); math.Abs(lower+2.944) > 1e-3 || math.Abs(upper-2.944) > 1e-3 {
+		t.Errorf("expected bounds of ±2.944, got %g %g", lower, upper)
+	}
+	tests := []struct {
+		r        results
+		decision int
+	}{
+		{results{}, 0},
+		{results{wins: 3}, 0},
+		{results{wins: 65, losses: 35}, 1},
+		{results{wins: 30, losses: 70}, -1},
+		{results{wins: 52, losses: 48}, 0},
+		{results{wins: 30}, 1},
+	}
+	for _, test := range tests {
+		if d := s.decide(test.r); d != test.decision {
+			t.Errorf("%+v: expected %d, got %d with LLR %g", test.r, test.decision, d, s.llr(test.r))
+		}
+	}
+}
@@ -10,11 +10,26 @@ import (
 )
 
 var port = flag.String("port", ":8100", "port to run service on")
+var data = flag.String("data", "", "file to keep games in, games are kept in memory if unset")
 
 func init() {
 	flag.Parse()
 }
 
 func main() {
-	log.Fatal(http.ListenAndServe(*port, server.MuxerAPIv1()))
+	var store server.Store = server.NewMemoryStore()
+	var file *server.FileStore
+	if *data != "" {
+		s, err := server.NewFileStore(*data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store, file = s, s
+	}
+	err := http.ListenAndServe(*port, server.MuxerAPI(store))
+	// log.Fatal exits without running deferred calls, so the file is closed first
+	if file != nil {
+		file.Close()
+	}
+	log.Fatal(err)
 }
@@ -0,0 +1,69 @@
+// gtp-bridge connects Go Text Protocol programs to gobotgo games.
+//
+// By default it seats a GTP engine in a game:
+//
+//	gtp-bridge -url http://localhost:8100 gnugo --mode gtp
+//
+// With -serve the game itself is presented as a GTP engine on stdin and stdout,
+// so a GTP GUI can play against whoever is seated on the server:
+//
+//	gtp-bridge -url http://localhost:8100 -serve
+//
+// With -key the engine plays as the registered bot with that API key.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/gophergala2016/gobotgo/client"
+	"github.com/gophergala2016/gobotgo/client/gtp"
+)
+
+var url = flag.String("url", "http://localhost:8100", "Root URL of gobotgo service")
+var serve = flag.Bool("serve", false, "Serve the game as a GTP engine on stdin and stdout")
+var key = flag.String("key", "", "API key of the registered player to play as")
+
+func init() {
+	flag.Parse()
+}
+
+func main() {
+	if *serve {
+		c := connect()
+		if err := gtp.Serve(c, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.NArg() == 0 {
+		log.Fatal("usage: gtp-bridge [-url URL] [-key KEY] engine [args...]")
+	}
+	e, err := gtp.Start(flag.Arg(0), flag.Args()[1:]...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer e.Close()
+
+	c := connect()
+	if err := gtp.Play(context.Background(), c, e); err != nil {
+		log.Println(err)
+		return
+	}
+	score := c.Score()
+	log.Printf("Score: b: %.1f, w: %.1f", score.Black.Total, score.White.Total)
+	log.Println("Game won by", score.Winner)
+}
+
+func connect() *client.Client {
+	log.Println("Connecting...")
+	c, err := client.NewPlayer(*url, *key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Player", c.ID(), "playing", c.Color())
+	return c
+}
@@ -1,6 +1,7 @@
 
 var gameRoot = "http://localhost:8100/api/v1/game/";
 var startGame = gameRoot + "start/";
+var gamesRoot = "http://localhost:8100/api/v2/games/";
 var size = 19;
 var test_data = [];
 
@@ -103,6 +104,39 @@ $('.pass').click(function () {
     $.post(sendMove, "[]", okay).fail(connectError);
 });
 
+// Watch the oldest game being played, following each turn as it is played
+$('.watch').click(function () {
+    $.get(gamesRoot, function(data) {
+        if (data.length == 0) {
+            showToast("No games are being played", 2000);
+            return;
+        }
+        showToast("Watching game " + data[0]["game"], 2000);
+        $('.new').hide();
+        $.get(gamesRoot + data[0]["game"], watchTurn).fail(connectError);
+    }).fail(connectError);
+});
+
+function watchTurn(data) {
+    boardRefresh(data, "watching");
+    if (data["result"]) {
+        showToast("Game won by " + data["result"]["winner"], 4000);
+        return;
+    }
+    waitTurn(data);
+}
+
+// Wait for the turn after the watched state, asking again when the wait gives up with 204 No Content
+function waitTurn(watched) {
+    $.get(gamesRoot + watched["game"] + "/wait?turns=" + watched["turns"], function(data) {
+        if (!data) {
+            waitTurn(watched);
+            return;
+        }
+        watchTurn(data);
+    }).fail(connectError);
+}
+
 function okay() {
     showToast("Send okay", 2000)
 }
@@ -47,16 +47,24 @@ func (b Board) valid(m Move) error {
 
 // Apply adds the move and returns the number of captured pieces after clearing them from the board.
 func (b Board) Apply(m Move) (int, error) {
+	points, _, err := b.apply(m)
+	return points, err
+}
+
+// apply is Apply, also returning the change to the board's Hash
+func (b Board) apply(m Move) (int, uint64, error) {
 	if err := b.valid(m); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	if err := b.intersectionEmpty(m.Position); err != nil {
-		return 0, ErrSpotNotEmpty
+		return 0, 0, ErrSpotNotEmpty
 	}
 
 	stone := m.Player
 	b.set(m.Position, stone)
 
+	keys := zobristKeys(len(b))
+	hash := zobristKey(keys, m.X*len(b)+m.Y, stone)
 	points := 0
 	for _, p := range m.adjacent() {
 		switch {
@@ -66,17 +74,18 @@ func (b Board) Apply(m Move) (int, error) {
 			// Ignore adjacent positions of move color
 		default:
 			// Clear bounded of adjacent opponent color
-			if count := b.clearBounded(p); count > 0 {
+			if count, h := b.captureBounded(p, keys); count > 0 {
 				points += count
+				hash ^= h
 			}
 		}
 	}
 
 	if points == 0 && b.bounded(m.Position) {
 		b.set(m.Position, empty)
-		return 0, ErrSelfCapture
+		return 0, 0, ErrSelfCapture
 	}
-	return points, nil
+	return points, hash, nil
 }
 
 func (b Board) equal(c Board) error {
@@ -166,14 +175,22 @@ func (b Board) boundedMask(start Position) Board {
 
 // Counts bounded pieces at p and clears them
 func (b Board) clearBounded(start Position) int {
+	count, _ := b.captureBounded(start, zobristKeys(len(b)))
+	return count
+}
+
+// captureBounded clears the bounded pieces at p, returning their count and their Zobrist keys XORed together
+func (b Board) captureBounded(start Position, keys []uint64) (int, uint64) {
 	mask := b.boundedMask(start)
 	if mask == nil {
-		return 0
+		return 0, 0
 	}
 	count := 0
+	var hash uint64
 	sliced := b.slice()
 	for i, state := range mask.slice() {
 		if state != empty {
+			hash ^= zobristKey(keys, i, sliced[i])
 			sliced[i] = empty
 			count++
 		}
@@ -181,10 +198,17 @@ func (b Board) clearBounded(start Position) int {
 	if count == 0 {
 		panic("Mask was returned that was entirely empty")
 	}
-	return count
+	return count, hash
 }
 
+// Score returns the area (stones plus surrounded territory) held by each player
 func (b Board) Score() (blackPoints, whitePoints int) {
+	blackStones, whiteStones, blackTerritory, whiteTerritory := b.count()
+	return blackStones + blackTerritory, whiteStones + whiteTerritory
+}
+
+// count returns the stones on the board and the empty points surrounded by each player
+func (b Board) count() (blackStones, whiteStones, blackTerritory, whiteTerritory int) {
 	points := b.copy()
 	mask := newBoard(len(b))
 	for x := 0; x < len(b); x++ {
@@ -197,12 +221,17 @@ func (b Board) Score() (blackPoints, whitePoints int) {
 			}
 		}
 	}
-	for _, p := range points.slice() {
-		switch p {
-		case Black:
-			blackPoints++
-		case White:
-			whitePoints++
+	stones := b.slice()
+	for i, p := range points.slice() {
+		switch {
+		case p == Black && stones[i] == Black:
+			blackStones++
+		case p == White && stones[i] == White:
+			whiteStones++
+		case p == Black:
+			blackTerritory++
+		case p == White:
+			whiteTerritory++
 		}
 	}
 	return
@@ -593,6 +593,20 @@ func TestScore(t *testing.T) {
 	}
 }
 
+func TestCount(t *testing.T) {
+	board := sliceBoard([]Color{
+		empty, White, empty, White, empty,
+		Black, empty, empty, empty, White,
+		empty, Black, empty, empty, White,
+		Black, empty, empty, White, empty,
+		empty, Black, empty, White, empty,
+	}, 5)
+	bs, ws, bt, wt := board.count()
+	if bs != 4 || ws != 6 || bt != 2 || wt != 3 {
+		t.Errorf("expected stones 4-6 and territory 2-3, got %d-%d and %d-%d", bs, ws, bt, wt)
+	}
+}
+
 func ExampleColor_Dot() {
 	fmt.Println(None.Dot(), White.Dot(), Black.Dot(), empty.Dot())
 	// Output: . w b .
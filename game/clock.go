@@ -0,0 +1,255 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeSystem selects how a player's time is kept
+type TimeSystem int
+
+const (
+	// NoTime lets players take as long as they like
+	NoTime = TimeSystem(iota)
+	// Absolute gives each player a fixed amount of time for the whole game
+	Absolute
+	// Fischer adds an increment to a player's time after every move
+	Fischer
+	// ByoYomi follows the main time with periods, and a period is only used up by a move that overruns it
+	ByoYomi
+	// Canadian follows the main time with blocks of time in which a number of stones must be played
+	Canadian
+)
+
+func (t TimeSystem) String() string {
+	switch t {
+	case Absolute:
+		return "absolute"
+	case Fischer:
+		return "fischer"
+	case ByoYomi:
+		return "byoyomi"
+	case Canadian:
+		return "canadian"
+	default:
+		return "none"
+	}
+}
+
+func (t TimeSystem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *TimeSystem) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	system, err := ParseTimeSystem(s)
+	*t = system
+	return err
+}
+
+// ParseTimeSystem reads a time system by name, as written by String
+func ParseTimeSystem(s string) (TimeSystem, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return NoTime, nil
+	case "absolute":
+		return Absolute, nil
+	case "fischer":
+		return Fischer, nil
+	case "byoyomi", "byo-yomi":
+		return ByoYomi, nil
+	case "canadian":
+		return Canadian, nil
+	}
+	return NoTime, fmt.Errorf("%s is not a valid time system", s)
+}
+
+// TimeControl is how much time each player has to play
+type TimeControl struct {
+	System TimeSystem
+	// Main is the time before any overtime
+	Main time.Duration
+	// Increment is added after every move with Fischer timing
+	Increment time.Duration
+	// Period is the length of a byo-yomi period or of a Canadian overtime block
+	Period time.Duration
+	// Periods is the number of byo-yomi periods
+	Periods int
+	// Stones must be played in each Canadian overtime block
+	Stones int
+}
+
+// jsonTimeControl is a TimeControl with durations in seconds
+type jsonTimeControl struct {
+	System    TimeSystem `json:"system"`
+	Main      float64    `json:"main,omitempty"`
+	Increment float64    `json:"increment,omitempty"`
+	Period    float64    `json:"period,omitempty"`
+	Periods   int        `json:"periods,omitempty"`
+	Stones    int        `json:"stones,omitempty"`
+}
+
+func (tc TimeControl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonTimeControl{
+		tc.System,
+		tc.Main.Seconds(),
+		tc.Increment.Seconds(),
+		tc.Period.Seconds(),
+		tc.Periods,
+		tc.Stones,
+	})
+}
+
+func (tc *TimeControl) UnmarshalJSON(data []byte) error {
+	var j jsonTimeControl
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*tc = TimeControl{
+		j.System,
+		seconds(j.Main),
+		seconds(j.Increment),
+		seconds(j.Period),
+		j.Periods,
+		j.Stones,
+	}
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Check returns an error if players could never move in time
+func (tc TimeControl) Check() error {
+	switch {
+	case tc.Main < 0 || tc.Increment < 0 || tc.Period < 0 || tc.Periods < 0 || tc.Stones < 0:
+		return fmt.Errorf("%s time control cannot be negative", tc.System)
+	case (tc.System == Absolute || tc.System == Fischer) && tc.Main == 0:
+		return fmt.Errorf("%s time control needs main time", tc.System)
+	case tc.System == ByoYomi && (tc.Period == 0 || tc.Periods == 0):
+		return fmt.Errorf("%s time control needs periods", tc.System)
+	case tc.System == Canadian && (tc.Period == 0 || tc.Stones == 0):
+		return fmt.Errorf("%s time control needs a period and stones", tc.System)
+	}
+	return nil
+}
+
+// Clock is the time a player has left
+type Clock struct {
+	// Main is the main time left
+	Main time.Duration
+	// Overtime is the time left in the current byo-yomi period or Canadian block
+	Overtime time.Duration
+	// Periods is the number of byo-yomi periods left, including the current one
+	Periods int
+	// Stones is the number of stones left to play in the current Canadian block
+	Stones int
+}
+
+// jsonClock is a Clock with durations in seconds
+type jsonClock struct {
+	Main     float64 `json:"main"`
+	Overtime float64 `json:"overtime,omitempty"`
+	Periods  int     `json:"periods,omitempty"`
+	Stones   int     `json:"stones,omitempty"`
+}
+
+func (c Clock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonClock{c.Main.Seconds(), c.Overtime.Seconds(), c.Periods, c.Stones})
+}
+
+func (c *Clock) UnmarshalJSON(data []byte) error {
+	var j jsonClock
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*c = Clock{seconds(j.Main), seconds(j.Overtime), j.Periods, j.Stones}
+	return nil
+}
+
+// NewClock returns the time a player starts the game with
+func (tc TimeControl) NewClock() Clock {
+	c := Clock{Main: tc.Main}
+	switch tc.System {
+	case ByoYomi:
+		c.Overtime, c.Periods = tc.Period, tc.Periods
+	case Canadian:
+		c.Overtime, c.Stones = tc.Period, tc.Stones
+	}
+	return c
+}
+
+// Left returns how long the player can think before losing on time
+func (c Clock) Left(tc TimeControl) time.Duration {
+	switch tc.System {
+	case ByoYomi:
+		if c.Periods == 0 {
+			return c.Main
+		}
+		return c.Main + c.Overtime + time.Duration(c.Periods-1)*tc.Period
+	case Canadian:
+		return c.Main + c.Overtime
+	default:
+		return c.Main
+	}
+}
+
+// Elapse takes d from the clock while the player thinks,
+// and returns false when the player has run out of time
+func (c Clock) Elapse(tc TimeControl, d time.Duration) (Clock, bool) {
+	if d < c.Main {
+		c.Main -= d
+		return c, true
+	}
+	d -= c.Main
+	c.Main = 0
+	switch tc.System {
+	case ByoYomi:
+		for c.Periods > 0 && d >= c.Overtime {
+			d -= c.Overtime
+			c.Periods--
+			c.Overtime = tc.Period
+		}
+		if c.Periods == 0 {
+			c.Overtime = 0
+			return c, false
+		}
+		c.Overtime -= d
+	case Canadian:
+		if d >= c.Overtime {
+			c.Overtime = 0
+			return c, false
+		}
+		c.Overtime -= d
+	default:
+		return c, false
+	}
+	return c, true
+}
+
+// Move takes d from the clock for a move,
+// then adds any time the player earns for moving in time
+func (c Clock) Move(tc TimeControl, d time.Duration) (Clock, bool) {
+	c, ok := c.Elapse(tc, d)
+	if !ok {
+		return c, false
+	}
+	switch {
+	case tc.System == Fischer:
+		c.Main += tc.Increment
+	case c.Main > 0:
+	case tc.System == ByoYomi:
+		c.Overtime = tc.Period
+	case tc.System == Canadian:
+		if c.Stones--; c.Stones == 0 {
+			c.Overtime, c.Stones = tc.Period, tc.Stones
+		}
+	}
+	return c, true
+}
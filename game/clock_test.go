@@ -0,0 +1,132 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name     string
+		tc       TimeControl
+		moves    []time.Duration
+		expected Clock
+		ok       bool
+	}{
+		{
+			"absolute",
+			TimeControl{System: Absolute, Main: 60 * s},
+			[]time.Duration{20 * s, 30 * s},
+			Clock{Main: 10 * s},
+			true,
+		},
+		{
+			"absolute flag",
+			TimeControl{System: Absolute, Main: 60 * s},
+			[]time.Duration{20 * s, 40 * s},
+			Clock{},
+			false,
+		},
+		{
+			"fischer",
+			TimeControl{System: Fischer, Main: 60 * s, Increment: 10 * s},
+			[]time.Duration{50 * s, 15 * s},
+			Clock{Main: 15 * s},
+			true,
+		},
+		{
+			"byoyomi period kept",
+			TimeControl{System: ByoYomi, Main: 10 * s, Period: 30 * s, Periods: 3},
+			[]time.Duration{35 * s, 29 * s},
+			Clock{Overtime: 30 * s, Periods: 3},
+			true,
+		},
+		{
+			"byoyomi periods used",
+			TimeControl{System: ByoYomi, Main: 10 * s, Period: 30 * s, Periods: 3},
+			[]time.Duration{75 * s},
+			Clock{Overtime: 30 * s, Periods: 1},
+			true,
+		},
+		{
+			"byoyomi flag",
+			TimeControl{System: ByoYomi, Main: 10 * s, Period: 30 * s, Periods: 3},
+			[]time.Duration{75 * s, 30 * s},
+			Clock{},
+			false,
+		},
+		{
+			"canadian block",
+			TimeControl{System: Canadian, Main: 10 * s, Period: 60 * s, Stones: 3},
+			[]time.Duration{20 * s, 20 * s},
+			Clock{Overtime: 30 * s, Stones: 1},
+			true,
+		},
+		{
+			"canadian block reset",
+			TimeControl{System: Canadian, Main: 10 * s, Period: 60 * s, Stones: 2},
+			[]time.Duration{20 * s, 20 * s},
+			Clock{Overtime: 60 * s, Stones: 2},
+			true,
+		},
+		{
+			"canadian flag",
+			TimeControl{System: Canadian, Main: 10 * s, Period: 60 * s, Stones: 3},
+			[]time.Duration{40 * s, 30 * s},
+			Clock{Stones: 2},
+			false,
+		},
+	}
+	for _, test := range tests {
+		c, ok := test.tc.NewClock(), true
+		for _, d := range test.moves {
+			if c, ok = c.Move(test.tc, d); !ok {
+				break
+			}
+		}
+		if ok != test.ok || c != test.expected {
+			t.Errorf("%s: expected %+v %v, got %+v %v", test.name, test.expected, test.ok, c, ok)
+		}
+	}
+}
+
+func TestClockLeft(t *testing.T) {
+	tc := TimeControl{System: ByoYomi, Main: 10 * time.Second, Period: 30 * time.Second, Periods: 3}
+	c := tc.NewClock()
+	if l := c.Left(tc); l != 100*time.Second {
+		t.Errorf("expected 100s to start, got %s", l)
+	}
+	if _, ok := c.Elapse(tc, c.Left(tc)); ok {
+		t.Error("expected the flag to fall once all time has elapsed")
+	}
+	if _, ok := c.Elapse(tc, c.Left(tc)-time.Millisecond); !ok {
+		t.Error("expected time left just before the flag falls")
+	}
+}
+
+func TestTimeControlJSON(t *testing.T) {
+	tc := TimeControl{System: Fischer, Main: 5 * time.Minute, Increment: 1500 * time.Millisecond}
+	b, err := json.Marshal(tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if `{"system":"fischer","main":300,"increment":1.5}` != string(b) {
+		t.Errorf("unexpected time control JSON %s", string(b))
+	}
+	var parsed TimeControl
+	if err := json.Unmarshal(b, &parsed); err != nil || parsed != tc {
+		t.Errorf("expected %+v, got %+v '%v'", tc, parsed, err)
+	}
+	for _, tc := range []TimeControl{
+		{System: Absolute},
+		{System: ByoYomi, Main: time.Minute, Periods: 5},
+		{System: Canadian, Main: time.Minute, Period: time.Minute},
+		{System: Fischer, Main: time.Minute, Increment: -time.Second},
+	} {
+		if err := tc.Check(); err == nil {
+			t.Errorf("expected %+v to be invalid", tc)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package game
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 )
 
 type Stones struct {
@@ -27,13 +28,40 @@ const (
 	ErrNoStones     = MoveError("Player out of stones")
 	ErrRepeatState  = MoveError("Move recreates previous state")
 	ErrSelfCapture  = MoveError("Move causes self capture")
+	ErrSetup        = MoveError("Stones can only be placed before play")
+	ErrNoUndo       = MoveError("No turns to undo")
+	ErrHandicap     = MoveError("Black must place handicap stones")
 )
 
+// RepeatError is returned by State.Move when the move recreates the position
+// reached after an earlier move. Move 0 is the starting position.
+type RepeatError struct {
+	Move int
+}
+
+func (r RepeatError) Error() string {
+	return fmt.Sprintf("%s (move %d)", ErrRepeatState, r.Move)
+}
+
+// Unwrap allows errors.Is(err, ErrRepeatState)
+func (r RepeatError) Unwrap() error {
+	return ErrRepeatState
+}
+
 type LastMove struct {
 	Move
 	PiecesRemoved int
 }
 
+// Turn is a move or pass made during a game
+type Turn struct {
+	Move
+	Pass     bool `json:"pass,omitempty"`
+	Resign   bool `json:"resign,omitempty"`
+	Timeout  bool `json:"timeout,omitempty"`
+	Captured int  `json:"captured"`
+}
+
 type State struct {
 	current  Board
 	previous Board
@@ -43,11 +71,36 @@ type State struct {
 	pieces   int
 	stones   map[Color]*Stones
 	last     LastMove
+	rules    Rules
+	// positions reached after each move or pass, starting with the empty board
+	positions []position
+	turns     []Turn
+	setup     []Move
+	// handicap is the number of free handicap stones Black has still to place
+	handicap int
+	// result is set when the game ends without counting the board
+	result *Result
 }
 
-func New(size, pieces int) *State {
+// position is a board reached during the game, kept to tell a repeat from a hash collision
+type position struct {
+	hash   uint64
+	player Color
+	board  Board
+}
+
+// New starts a game on a size x size board with pieces stones per player.
+// Fixed handicap stones are placed, and free handicap stones are placed by Black's first moves.
+// New returns an error if the board has no points or the rules cannot be used on it, see Rules.Check.
+func New(size, pieces int, rules Rules) (*State, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("%d is not a valid board size", size)
+	}
+	if err := rules.Check(size); err != nil {
+		return nil, err
+	}
 	c := newBoard(size)
-	return &State{
+	s := &State{
 		current:  c,
 		previous: nil,
 		player:   Black,
@@ -58,7 +111,28 @@ func New(size, pieces int) *State {
 			White: {pieces, 0},
 			Black: {pieces, 0},
 		},
+		rules:     rules,
+		positions: []position{{c.Hash(), Black, c}},
 	}
+	switch h := rules.Handicap; {
+	case h.Stones == 0:
+	case h.Placement == FixedPlacement:
+		ps, _ := FixedHandicap(size, h.Stones)
+		s.Place(Black, ps...)
+		s.SetFirst(White)
+	default:
+		s.handicap = h.Stones
+	}
+	return s, nil
+}
+
+// MustNew is New for a board and rules known to be valid, and panics if they are not
+func MustNew(size, pieces int, rules Rules) *State {
+	s, err := New(size, pieces, rules)
+	if err != nil {
+		panic(err)
+	}
+	return s
 }
 
 func (s *State) valid(m Move) error {
@@ -85,12 +159,21 @@ func (s *State) Pass(player Color) error {
 	if player != s.player {
 		return ErrWrongPlayer
 	}
+	if s.handicap > 0 {
+		return ErrHandicap
+	}
 	if s.current.equal(s.previous) == nil {
 		s.over = true
+		s.turns = append(s.turns, Turn{Move: Move{Player: player}, Pass: true})
 		return ErrGameOver
 	}
+	s.turns = append(s.turns, Turn{Move: Move{Player: player}, Pass: true})
 	s.previous = s.current
 	s.player = player.Opponent()
+	s.positions = append(s.positions, position{s.hash(), s.player, s.current})
+	if s.rules.PassStones {
+		s.stones[s.player].Captured++
+	}
 	return nil
 }
 
@@ -98,13 +181,20 @@ func (s *State) Move(m Move) error {
 	if err := s.valid(m); err != nil {
 		return err
 	}
+	if s.handicap > 0 {
+		return s.placeHandicap(m.Position)
+	}
 	b := s.current.copy()
-	captured, err := b.Apply(m)
+	captured, change, err := b.apply(m)
 	if err != nil {
 		return err
 	}
 	if b.equal(s.previous) == nil {
-		return ErrRepeatState
+		return RepeatError{len(s.positions) - 2}
+	}
+	p := position{s.hash() ^ change, m.Player.Opponent(), b}
+	if n, ok := s.repeats(p); ok {
+		return RepeatError{n}
 	}
 	s.previous = s.current
 	s.current = b
@@ -112,14 +202,126 @@ func (s *State) Move(m Move) error {
 	s.stones[m.Player].Captured += captured
 	s.player = m.Player.Opponent()
 	s.last = LastMove{m, captured}
+	s.positions = append(s.positions, p)
+	s.turns = append(s.turns, Turn{Move: m, Captured: captured})
+	return nil
+}
+
+// Undo takes back the last move or pass, restoring the board, stones, captures,
+// player to move and ko state exactly as they were before it was played.
+// A finished game cannot be undone.
+func (s *State) Undo() error {
+	if s.over {
+		return ErrGameOver
+	}
+	if len(s.turns) == 0 {
+		return ErrNoUndo
+	}
+	r := s.Record()
+	previous, err := Replay(r, len(r.Turns)-1)
+	if err != nil {
+		return err
+	}
+	*s = *previous
 	return nil
 }
 
-func (s *State) Score() (black, white int) {
-	black, white = s.current.Score()
-	black += s.stones[Black].Captured
-	white += s.stones[White].Captured
-	return
+// placeHandicap puts one of Black's free handicap stones on the board
+func (s *State) placeHandicap(p Position) error {
+	if err := s.Place(Black, p); err != nil {
+		return err
+	}
+	s.handicap--
+	if s.handicap == 0 {
+		s.SetFirst(White)
+	}
+	return nil
+}
+
+// Place puts setup stones of color c on the board before play begins.
+// Setup stones do not count against the remaining pieces.
+func (s *State) Place(c Color, ps ...Position) error {
+	if len(s.turns) > 0 || s.over {
+		return ErrSetup
+	}
+	b := s.current.copy()
+	for _, p := range ps {
+		m := Move{c, p}
+		if err := b.valid(m); err != nil {
+			return err
+		}
+		if err := b.intersectionEmpty(p); err != nil {
+			return ErrSpotNotEmpty
+		}
+		b.set(p, c)
+	}
+	s.current = b
+	s.positions = []position{{b.Hash(), s.player, b}}
+	for _, p := range ps {
+		s.setup = append(s.setup, Move{c, p})
+	}
+	return nil
+}
+
+// repeats returns the move number of an earlier position p would repeat under the ko rule
+func (s *State) repeats(p position) (int, bool) {
+	for i, q := range s.positions {
+		switch {
+		case q.hash != p.hash:
+		case q.board.equal(p.board) != nil:
+			// A hash collision, not a repeat
+		case s.rules.Ko == PositionalSuperko:
+			return i, true
+		case s.rules.Ko == SituationalSuperko && q.player == p.player:
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// hash returns the Zobrist hash of the current board
+func (s *State) hash() uint64 {
+	return s.positions[len(s.positions)-1].hash
+}
+
+// Score counts the current board under the rules of the game
+func (s *State) Score() Score {
+	return s.rules.Score(s.current, *s.stones[Black], *s.stones[White])
+}
+
+// SetFirst chooses the player to make the first move, such as White after handicap stones are placed
+func (s *State) SetFirst(c Color) error {
+	if len(s.turns) > 0 || s.over {
+		return ErrSetup
+	}
+	s.player = c
+	s.positions[0].player = c
+	return nil
+}
+
+// Rules returns the rules the game is played under
+func (s *State) Rules() Rules {
+	return s.rules
+}
+
+// Size returns the width of the board
+func (s *State) Size() int {
+	return s.size
+}
+
+// Over reports whether the game has ended
+func (s *State) Over() bool {
+	return s.over
+}
+
+// History returns every move and pass played, in order
+func (s *State) History() []Turn {
+	return append([]Turn{}, s.turns...)
+}
+
+// Setup returns the stones placed before play began
+func (s *State) Setup() []Move {
+	return append([]Move{}, s.setup...)
 }
 
 type PublicState struct {
@@ -128,17 +330,33 @@ type PublicState struct {
 	Black         Stones   `json:"black"`
 	White         Stones   `json:"white"`
 	LastMove      LastMove `json:"lastmove,omitempty"`
+	Rules         Rules    `json:"rules"`
+	// HandicapLeft is the number of free handicap stones Black has still to place
+	HandicapLeft int `json:"handicapleft,omitempty"`
+	// Result is set once the game is over
+	Result *Result `json:"result,omitempty"`
 }
 
-func (s *State) MarshalJSON() ([]byte, error) {
-	data := PublicState{
+// Public returns the state as sent to players
+func (s *State) Public() PublicState {
+	p := PublicState{
 		s.current,
 		s.player,
 		*s.stones[Black],
 		*s.stones[White],
 		s.last,
+		s.rules,
+		s.handicap,
+		nil,
 	}
-	return json.Marshal(data)
+	if r, ok := s.Result(); ok {
+		p.Result = &r
+	}
+	return p
+}
+
+func (s *State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Public())
 }
 
 func (s *State) UnmarshalJSON(data []byte) error {
@@ -1,9 +1,13 @@
 package game
 
-import "testing"
+import (
+	"errors"
+	"math"
+	"testing"
+)
 
 func TestTurnOrder(t *testing.T) {
-	s := New(4, 20)
+	s := MustNew(4, 20, DefaultRules)
 	switch {
 	case s.player != Black:
 		t.Error("Expected first play to be Black")
@@ -36,7 +40,7 @@ func TestRemainingCountdown(t *testing.T) {
 		{Move{Black, Position{2, 1}}, 0, 1},
 		{Move{White, Position{2, 0}}, 0, 0},
 	}
-	s := New(4, 2)
+	s := MustNew(4, 2, DefaultRules)
 	for i, test := range tests {
 		if err := s.Move(test.Move); err != nil {
 			t.Fatalf("Unexpected error for move %d:%+v:%s", i, test.Move, err.Error())
@@ -60,7 +64,7 @@ func TestCannotLoopGameState(t *testing.T) {
 		{Move{Black, Position{0, 0}}, nil},
 	}
 	size := 4
-	s := New(size, 20)
+	s := MustNew(size, 20, DefaultRules)
 	s.current = sliceBoard([]Color{
 		empty, Black, White, empty,
 		Black, empty, empty, White,
@@ -68,15 +72,104 @@ func TestCannotLoopGameState(t *testing.T) {
 		empty, empty, empty, empty,
 	}, size)
 	for i, test := range tests {
-		if err := s.Move(test.Move); err != test.err {
+		if err := s.Move(test.Move); !errors.Is(err, test.err) {
 			t.Errorf("for move %d expected error '%s' got '%s'", i, test.err, err)
 		}
 	}
 }
 
+func TestSuperko(t *testing.T) {
+	// Three kos at rows 1, 3 and 5
+	initial := []Color{
+		empty, Black, White, empty, empty, empty, empty,
+		Black, White, empty, White, empty, empty, empty,
+		empty, Black, White, empty, empty, empty, empty,
+		Black, empty, Black, White, empty, empty, empty,
+		empty, Black, White, empty, empty, empty, empty,
+		Black, White, empty, White, empty, empty, empty,
+		empty, Black, White, empty, empty, empty, empty,
+	}
+	pass := Position{-1, -1}
+	// Cycles back to the initial board with Black to move
+	cycle := []Move{
+		{Black, Position{1, 2}},
+		{White, Position{3, 1}},
+		{Black, Position{5, 2}},
+		{White, Position{1, 1}},
+		{Black, Position{3, 2}},
+		{White, Position{5, 1}},
+	}
+	// Cycles back to the initial board with White to move
+	passing := []Move{
+		{Black, Position{1, 2}},
+		{White, Position{3, 1}},
+		{Black, Position{5, 2}},
+		{White, Position{1, 1}},
+		{Black, pass},
+		{White, Position{5, 1}},
+		{Black, Position{3, 2}},
+	}
+	tests := []struct {
+		name  string
+		ko    KoRule
+		moves []Move
+		err   error
+	}{
+		{"simple cycle", SimpleKo, cycle, nil},
+		{"positional cycle", PositionalSuperko, cycle, RepeatError{0}},
+		{"situational cycle", SituationalSuperko, cycle, RepeatError{0}},
+		{"simple passing", SimpleKo, passing, nil},
+		{"positional passing", PositionalSuperko, passing, RepeatError{0}},
+		{"situational passing", SituationalSuperko, passing, nil},
+	}
+	for _, test := range tests {
+		s := MustNew(7, 100, Rules{Ko: test.ko})
+		s.current = sliceBoard(initial, 7)
+		s.positions = []position{{s.current.Hash(), Black, s.current}}
+		for i, m := range test.moves {
+			var err error
+			if m.Position == pass {
+				err = s.Pass(m.Player)
+			} else {
+				err = s.Move(m)
+			}
+			expected := test.err
+			if i < len(test.moves)-1 {
+				expected = nil
+			}
+			if err != expected {
+				t.Errorf("%s: for move %d expected error '%v', got '%v'", test.name, i, expected, err)
+			}
+		}
+	}
+}
+
+func TestHashUpdate(t *testing.T) {
+	s := MustNew(9, 300, Rules{Ko: PositionalSuperko})
+	for i, m := range randomMoves(9, 200) {
+		if err := s.Move(m); err != nil {
+			continue
+		}
+		if expected := s.current.Hash(); s.hash() != expected {
+			t.Fatalf("move %d: expected hash %x, got %x\n%s", i, expected, s.hash(), s.current)
+		}
+	}
+}
+
+func TestHashCollision(t *testing.T) {
+	s := MustNew(5, 30, Rules{Ko: PositionalSuperko})
+	b := s.current.copy()
+	b.set(Position{2, 2}, Black)
+	// The empty starting board has the same hash as the board after the move
+	s.positions[0].hash = b.Hash()
+	if err := s.Move(Move{Black, Position{2, 2}}); err != nil {
+		t.Errorf("expected a hash collision not to be a repeat, got '%s'", err)
+	}
+}
+
 func TestDoublePassEndsGame(t *testing.T) {
 	size := 4
-	s := New(size, 30)
+	s := MustNew(size, 30, DefaultRules)
 	s.current = sliceBoard([]Color{
 		empty, Black, White, empty,
 		Black, empty, empty, White,
@@ -137,7 +230,7 @@ func TestDoublePassEndsGame(t *testing.T) {
 }
 
 func TestPassBlackDoesNotEntGame(t *testing.T) {
-	s := New(5, 20)
+	s := MustNew(5, 20, DefaultRules)
 	if err := s.Pass(Black); err != nil {
 		t.Fatalf("Did not expect to be unable to pass black, '%s'", err)
 	}
@@ -146,7 +239,7 @@ func TestPassBlackDoesNotEntGame(t *testing.T) {
 func TestOutOfStonesEnds(t *testing.T) {
 	stoneCount := 4
 	size := 4
-	s := New(size, stoneCount)
+	s := MustNew(size, stoneCount, DefaultRules)
 	for i := 0; i < stoneCount; i++ {
 		if err := s.Pass(Black); err != nil {
 			t.Errorf("Unexepected error for %d:Pass(Black), got '%s'", i, err.Error())
@@ -189,9 +282,16 @@ func TestOutOfStonesEnds(t *testing.T) {
 }
 
 func TestScoring(t *testing.T) {
-	score := struct {
-		black, white int
-	}{5, 2}
+	tests := []struct {
+		rules        Rules
+		black, white float64
+		winner       Color
+	}{
+		{Rules{Komi: 0, Scoring: AreaScoring}, 4, 2, Black},
+		{Rules{Komi: 7.5, Scoring: AreaScoring}, 4, 9.5, White},
+		{Rules{Komi: 0.5, Scoring: TerritoryScoring}, 2, 0.5, Black},
+		{Rules{Komi: 2, Scoring: AreaScoring}, 4, 4, None},
+	}
 
 	// assume alternating moves
 	moves := []Position{
@@ -200,26 +300,47 @@ func TestScoring(t *testing.T) {
 		{0, 2}, {0, 3},
 		{0, 0}, {0, 4},
 	}
-	p := Black
-	s := New(5, 100)
-	for i, m := range moves {
-		move := Move{p, m}
-		if err := s.Move(move); err != nil {
-			t.Fatalf("failed move %d:%v, got '%s'", i, move, err.Error())
+	for _, test := range tests {
+		p := Black
+		s := MustNew(5, 100, test.rules)
+		for i, m := range moves {
+			move := Move{p, m}
+			if err := s.Move(move); err != nil {
+				t.Fatalf("failed move %d:%v, got '%s'", i, move, err.Error())
+			}
+			p = p.Opponent()
+		}
+
+		score := s.Score()
+		if score.Black.Total != test.black || score.White.Total != test.white {
+			t.Errorf("unmatched score for %+v, expected %.1f-%.1f, got %.1f-%.1f", test.rules, test.black, test.white, score.Black.Total, score.White.Total)
+		}
+		if score.Margin != test.black-test.white {
+			t.Errorf("expected margin %.1f, got %.1f", test.black-test.white, score.Margin)
+		}
+		if score.Winner != test.winner {
+			t.Errorf("expected winner %s for %+v, got %s", test.winner, test.rules, score.Winner)
 		}
-		p = p.Opponent()
 	}
+}
 
-	t.Log(s.stones[Black], s.stones[White])
-	b, w := s.Score()
-	if score.black != b || score.white != w {
-		t.Errorf("unmatched score, expected %d-%d, got %d-%d", score.black, score.white, b, w)
+func TestPassStones(t *testing.T) {
+	s := MustNew(5, 100, Rules{Komi: 0.5, Scoring: TerritoryScoring, PassStones: true})
+	if err := s.Pass(Black); err != nil {
+		t.Fatalf("unexpected error passing, got '%s'", err)
+	}
+	if s.stones[White].Captured != 1 {
+		t.Errorf("expected White to receive a pass stone, has %d", s.stones[White].Captured)
+	}
+	score := s.Score()
+	if score.White.Total != 1.5 || score.Winner != White {
+		t.Errorf("expected White to win by 1.5, got %+v", score)
 	}
 }
 
 func TestLastMove(t *testing.T) {
 	size := 4
-	s := New(size, 30)
+	s := MustNew(size, 30, DefaultRules)
 	s.current = sliceBoard([]Color{
 		empty, Black, White, empty,
 		Black, White, empty, White,
@@ -251,3 +372,287 @@ func TestLastMove(t *testing.T) {
 		t.Errorf("expected 0 pieces to be removed, got %d", s.last.PiecesRemoved)
 	}
 }
+
+func TestPlaceAndHistory(t *testing.T) {
+	s := MustNew(5, 20, DefaultRules)
+	if err := s.Place(Black, Position{1, 1}, Position{3, 3}); err != nil {
+		t.Fatalf("unable to place setup stones: '%s'", err)
+	}
+	if err := s.Place(White, Position{1, 1}); err != ErrSpotNotEmpty {
+		t.Errorf("expected '%s' placing on a stone, got '%v'", ErrSpotNotEmpty, err)
+	}
+	if err := s.SetFirst(White); err != nil {
+		t.Fatalf("unable to set first player: '%s'", err)
+	}
+	if err := s.Move(Move{White, Position{2, 2}}); err != nil {
+		t.Fatalf("expected White to move first, got '%s'", err)
+	}
+	if err := s.Pass(Black); err != nil {
+		t.Fatalf("unexpected error passing, got '%s'", err)
+	}
+	if err := s.Place(Black, Position{0, 0}); err != ErrSetup {
+		t.Errorf("expected '%s' placing after play, got '%v'", ErrSetup, err)
+	}
+	if s.stones[Black].Remaining != 20 {
+		t.Errorf("expected setup stones not to be counted, %d remaining", s.stones[Black].Remaining)
+	}
+	history := s.History()
+	expected := []Turn{
+		{Move: Move{White, Position{2, 2}}},
+		{Move: Move{Player: Black}, Pass: true},
+	}
+	if len(history) != len(expected) || history[0] != expected[0] || history[1] != expected[1] {
+		t.Errorf("expected history %v, got %v", expected, history)
+	}
+	if len(s.Setup()) != 2 {
+		t.Errorf("expected 2 setup stones, got %v", s.Setup())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	s := MustNew(4, 20, DefaultRules)
+	if err := s.Place(Black, Position{3, 3}); err != nil {
+		t.Fatalf("unable to place setup stone: '%s'", err)
+	}
+	turns := []Move{
+		{Black, Position{0, 1}},
+		{White, Position{0, 0}},
+		{Black, Position{1, 0}},
+		{White, Position{2, 2}},
+	}
+	boards := []Board{s.current}
+	for i, m := range turns {
+		if err := s.Move(m); err != nil {
+			t.Fatalf("failed move %d:%v, got '%s'", i, m, err)
+		}
+		boards = append(boards, s.current)
+	}
+	if err := s.Pass(Black); err != nil {
+		t.Fatalf("unexpected error passing, got '%s'", err)
+	}
+	boards = append(boards, s.current)
+	if err := s.Pass(White); err != ErrGameOver {
+		t.Fatalf("expected second pass to end the game, got '%v'", err)
+	}
+	boards = append(boards, s.current)
+
+	r := s.Record()
+	if len(r.Turns) != 6 || r.Turns[2].Captured != 1 || !r.Turns[5].Pass {
+		t.Fatalf("unexpected history %v", r.Turns)
+	}
+	for n, b := range boards {
+		replayed, err := Replay(r, n)
+		if err != nil {
+			t.Fatalf("unable to replay %d turns: '%s'", n, err)
+		}
+		if err := replayed.current.equal(b); err != nil {
+			t.Errorf("replay of %d turns differs: %s\n%s", n, err, replayed.current)
+		}
+		if over := n == len(r.Turns); replayed.Over() != over {
+			t.Errorf("expected replay of %d turns to be over: %v", n, over)
+		}
+	}
+	if replayed, _ := Replay(r, 3); *replayed.stones[Black] != (Stones{18, 1}) {
+		t.Errorf("expected Black to have 18 remaining and 1 captured, got %v", *replayed.stones[Black])
+	}
+	if _, err := Replay(r, 7); err == nil {
+		t.Error("expected error replaying past the end of the game")
+	}
+	r.Size = 0
+	if _, err := Replay(r, 0); err == nil {
+		t.Error("expected error replaying a record without a board")
+	}
+}
+
+func TestUndo(t *testing.T) {
+	s := MustNew(4, 20, DefaultRules)
+	if err := s.Undo(); err != ErrNoUndo {
+		t.Errorf("expected '%s' with nothing played, got '%v'", ErrNoUndo, err)
+	}
+	moves := []Move{
+		{Black, Position{1, 0}},
+		{White, Position{0, 0}},
+		{Black, Position{3, 3}},
+		{White, Position{2, 0}},
+		{Black, Position{2, 1}},
+		{White, Position{3, 0}},
+	}
+	for i, m := range moves {
+		if err := s.Move(m); err != nil {
+			t.Fatalf("failed move %d:%v, got '%s'", i, m, err)
+		}
+	}
+	before := s.current.copy()
+	blackStones, whiteStones := *s.stones[Black], *s.stones[White]
+
+	// Black captures the two White stones at 2,0 and 3,0
+	capture := Move{Black, Position{3, 1}}
+	if err := s.Move(capture); err != nil {
+		t.Fatalf("failed capture %v, got '%s'", capture, err)
+	}
+	if s.stones[Black].Captured != 2 || s.current[3][0] != empty {
+		t.Fatalf("expected Black to capture 2 stones, got %v\n%s", *s.stones[Black], s.current)
+	}
+	if err := s.Undo(); err != nil {
+		t.Fatalf("unable to undo capture, got '%s'", err)
+	}
+	if err := s.current.equal(before); err != nil {
+		t.Errorf("undo did not restore captured stones: %s\n%s", err, s.current)
+	}
+	if *s.stones[Black] != blackStones || *s.stones[White] != whiteStones {
+		t.Errorf("expected stones %v-%v, got %v-%v", blackStones, whiteStones, *s.stones[Black], *s.stones[White])
+	}
+	if s.player != Black || s.last.Move != moves[len(moves)-1] {
+		t.Errorf("expected Black to move after %v, got %s after %v", moves[len(moves)-1], s.player, s.last.Move)
+	}
+	if len(s.History()) != len(moves) {
+		t.Errorf("expected %d turns after undo, got %d", len(moves), len(s.History()))
+	}
+
+	// Undoing a pass restores the ko state, so a second pass does not end the game
+	if err := s.Pass(Black); err != nil {
+		t.Fatalf("unexpected error passing, got '%s'", err)
+	}
+	if err := s.Undo(); err != nil {
+		t.Fatalf("unable to undo pass, got '%s'", err)
+	}
+	if err := s.Move(capture); err != nil {
+		t.Errorf("expected capture to be playable after undo, got '%s'", err)
+	}
+	if err := s.Pass(White); err != nil {
+		t.Errorf("expected pass after undo not to end the game, got '%s'", err)
+	}
+}
+
+func TestFixedHandicap(t *testing.T) {
+	tests := []struct {
+		size, stones int
+		first, last  Position
+	}{
+		{9, 2, Position{2, 6}, Position{6, 2}},
+		{13, 5, Position{3, 9}, Position{6, 6}},
+		{19, 4, Position{3, 15}, Position{15, 15}},
+		{19, 7, Position{3, 15}, Position{9, 9}},
+		{19, 9, Position{3, 15}, Position{9, 9}},
+	}
+	for _, test := range tests {
+		rules := DefaultRules
+		rules.Handicap = Handicap{Stones: test.stones}
+		s := MustNew(test.size, 180, rules)
+		setup := s.Setup()
+		if len(setup) != test.stones {
+			t.Fatalf("expected %d handicap stones on %dx%d, got %v", test.stones, test.size, test.size, setup)
+		}
+		if setup[0].Position != test.first || setup[len(setup)-1].Position != test.last {
+			t.Errorf("expected handicap %d on %dx%d from %v to %v, got %v", test.stones, test.size, test.size, test.first, test.last, setup)
+		}
+		if s.player != White {
+			t.Errorf("expected White to move first after handicap, got %s", s.player)
+		}
+	}
+	for _, test := range []struct{ size, stones int }{{9, 1}, {9, 10}, {7, 2}} {
+		rules := DefaultRules
+		rules.Handicap = Handicap{Stones: test.stones}
+		if err := rules.Check(test.size); err == nil {
+			t.Errorf("expected handicap %d on %dx%d to be invalid", test.stones, test.size, test.size)
+		}
+		if s, err := New(test.size, 180, rules); err == nil || s != nil {
+			t.Errorf("expected New to refuse handicap %d on %dx%d", test.stones, test.size, test.size)
+		}
+	}
+	if _, err := New(0, 180, DefaultRules); err == nil {
+		t.Error("expected New to refuse a board without points")
+	}
+	for _, komi := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 82, -82} {
+		rules := DefaultRules
+		rules.Komi = komi
+		if err := rules.Check(9); err == nil {
+			t.Errorf("expected komi %g to be invalid on 9x9", komi)
+		}
+	}
+}
+
+func TestFreeHandicap(t *testing.T) {
+	rules := DefaultRules
+	rules.Handicap = Handicap{Stones: 2, Placement: FreePlacement}
+	s := MustNew(5, 20, rules)
+	if err := s.Pass(Black); err != ErrHandicap {
+		t.Errorf("expected '%s' passing during placement, got '%v'", ErrHandicap, err)
+	}
+	if err := s.Move(Move{White, Position{0, 0}}); err != ErrWrongPlayer {
+		t.Errorf("expected '%s' for White during placement, got '%v'", ErrWrongPlayer, err)
+	}
+	for _, p := range []Position{{1, 1}, {3, 3}} {
+		if s.Public().HandicapLeft == 0 {
+			t.Fatalf("expected handicap stones left before %v", p)
+		}
+		if err := s.Move(Move{Black, p}); err != nil {
+			t.Fatalf("failed placing handicap %v, got '%s'", p, err)
+		}
+	}
+	if s.player != White || s.Public().HandicapLeft != 0 {
+		t.Fatalf("expected White to move after placement, got %s with %d left", s.player, s.Public().HandicapLeft)
+	}
+	if len(s.History()) != 0 || len(s.Setup()) != 2 {
+		t.Errorf("expected handicap in setup, got setup %v turns %v", s.Setup(), s.History())
+	}
+	if err := s.Move(Move{White, Position{2, 2}}); err != nil {
+		t.Fatalf("failed White's first move, got '%s'", err)
+	}
+
+	r, err := Replay(s.Record(), 0)
+	if err != nil {
+		t.Fatalf("unable to replay handicap game, got '%s'", err)
+	}
+	if r.player != White || r.current[3][3] != Black {
+		t.Errorf("expected White to move with handicap on board, got %s\n%s", r.player, r.current)
+	}
+	if err := s.Undo(); err != nil {
+		t.Fatalf("unable to undo White's move, got '%s'", err)
+	}
+	if s.player != White || s.Public().HandicapLeft != 0 {
+		t.Errorf("expected White to move after undo, got %s with %d left", s.player, s.Public().HandicapLeft)
+	}
+}
+
+func TestResign(t *testing.T) {
+	s := MustNew(5, 20, DefaultRules)
+	if _, ok := s.Result(); ok {
+		t.Fatal("expected no result while the game is played")
+	}
+	if err := s.Move(Move{Black, Position{2, 2}}); err != nil {
+		t.Fatalf("failed move, got '%s'", err)
+	}
+	// Black resigns on White's turn
+	if err := s.Resign(Black); err != nil {
+		t.Fatalf("unable to resign, got '%s'", err)
+	}
+	r, ok := s.Result()
+	if !ok || r.Winner != White || r.Reason != ResignReason || r.Score != nil {
+		t.Errorf("expected White to win by resignation, got %+v", r)
+	}
+	if err := s.Move(Move{White, Position{1, 1}}); err != ErrGameOver {
+		t.Errorf("expected '%s' after resigning, got '%v'", ErrGameOver, err)
+	}
+	replayed, err := Replay(s.Record(), 2)
+	if err != nil {
+		t.Fatalf("unable to replay resignation, got '%s'", err)
+	}
+	if r, _ := replayed.Result(); r.Winner != White || r.Reason != ResignReason {
+		t.Errorf("expected replayed resignation, got %+v", r)
+	}
+	// A finished game cannot be undone
+	if err := s.Undo(); err != ErrGameOver || !s.Over() {
+		t.Errorf("expected '%s' undoing a resignation, got '%v'", ErrGameOver, err)
+	}
+
+	// Passing out is scored
+	s = MustNew(5, 20, DefaultRules)
+	s.Move(Move{Black, Position{2, 2}})
+	s.Pass(White)
+	s.Pass(Black)
+	r, ok = s.Result()
+	if !ok || r.Reason != ScoreReason || r.Score == nil || r.Winner != r.Score.Winner {
+		t.Errorf("expected scored result, got %+v", r)
+	}
+}
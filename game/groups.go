@@ -0,0 +1,288 @@
+package game
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// Group is a chain of connected stones of one color, with the empty points next to it
+type Group struct {
+	Color     Color      `json:"color"`
+	Stones    []Position `json:"stones"`
+	Liberties []Position `json:"liberties"`
+}
+
+// InAtari reports whether the group would be captured by a stone on its last liberty
+func (g Group) InAtari() bool {
+	return len(g.Liberties) == 1
+}
+
+// Groups returns the groups on the board, ordered by their first stone
+func (b Board) Groups() []Group {
+	return b.Analyze().Groups()
+}
+
+// Liberties returns the liberties of the group with a stone at p, or nil if there is no stone at p
+func (b Board) Liberties(p Position) []Position {
+	return b.Analyze().Liberties(p)
+}
+
+// InAtari returns the groups of color c with a single liberty
+func (b Board) InAtari(c Color) []Group {
+	return b.Analyze().InAtari(c)
+}
+
+// Analysis keeps track of the groups on a board as moves are applied to it.
+// Applying a move only updates the groups next to it, so a bot can follow a game,
+// or read ahead on a copy of the board, without finding every group again.
+type Analysis struct {
+	board Board
+	size  int
+	// chains holds the chain of the stone at each point of the board's slice, nil if it is empty
+	chains []*chain
+}
+
+// chain is a group as an Analysis tracks it, with stones and liberties as indexes into the board's slice
+type chain struct {
+	color     Color
+	stones    []int
+	liberties points
+}
+
+// points is a set of indexes into a board's slice
+type points []uint64
+
+func newPoints(size int) points {
+	return make(points, (size*size+63)/64)
+}
+
+func (s points) add(i int)    { s[i/64] |= 1 << uint(i%64) }
+func (s points) remove(i int) { s[i/64] &^= 1 << uint(i%64) }
+
+func (s points) count() int {
+	n := 0
+	for _, w := range s {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// union adds the points in t to s
+func (s points) union(t points) {
+	for i, w := range t {
+		s[i] |= w
+	}
+}
+
+// indexes returns the points in s in order
+func (s points) indexes() []int {
+	var is []int
+	for i, w := range s {
+		for w != 0 {
+			is = append(is, i*64+bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+	return is
+}
+
+// Analyze finds the groups on a copy of the board, which the analysis then updates as moves are applied
+func (b Board) Analyze() *Analysis {
+	a := &Analysis{board: b.Copy(), size: len(b), chains: make([]*chain, len(b)*len(b))}
+	stones := a.board.slice()
+	for i, c := range stones {
+		if c == empty || a.chains[i] != nil {
+			continue
+		}
+		ch := &chain{color: c, liberties: newPoints(a.size)}
+		a.chains[i] = ch
+		frontier := []int{i}
+		for len(frontier) > 0 {
+			current := frontier[0]
+			frontier = frontier[1:]
+			ch.stones = append(ch.stones, current)
+			for _, adj := range a.adjacent(current) {
+				switch {
+				case adj < 0:
+				case stones[adj] == empty:
+					ch.liberties.add(adj)
+				case stones[adj] == c && a.chains[adj] == nil:
+					a.chains[adj] = ch
+					frontier = append(frontier, adj)
+				}
+			}
+		}
+	}
+	return a
+}
+
+// Board returns the analysed board, which changes as moves are applied
+func (a *Analysis) Board() Board {
+	return a.board
+}
+
+// adjacent returns the indexes of the points next to i, or -1 off the edge of the board
+func (a *Analysis) adjacent(i int) [4]int {
+	x, y := i/a.size, i%a.size
+	adj := [4]int{-1, -1, -1, -1}
+	if y+1 < a.size {
+		adj[0] = i + 1
+	}
+	if x+1 < a.size {
+		adj[1] = i + a.size
+	}
+	if y > 0 {
+		adj[2] = i - 1
+	}
+	if x > 0 {
+		adj[3] = i - a.size
+	}
+	return adj
+}
+
+func (a *Analysis) position(i int) Position {
+	return Position{i / a.size, i % a.size}
+}
+
+func (a *Analysis) positions(is []int) []Position {
+	ps := make([]Position, len(is))
+	for j, i := range is {
+		ps[j] = a.position(i)
+	}
+	return ps
+}
+
+func (a *Analysis) group(ch *chain) Group {
+	stones := append([]int{}, ch.stones...)
+	sort.Ints(stones)
+	return Group{Color: ch.color, Stones: a.positions(stones), Liberties: a.positions(ch.liberties.indexes())}
+}
+
+// Groups returns the groups on the board, ordered by their first stone
+func (a *Analysis) Groups() []Group {
+	var groups []Group
+	seen := map[*chain]bool{}
+	for _, ch := range a.chains {
+		if ch != nil && !seen[ch] {
+			seen[ch] = true
+			groups = append(groups, a.group(ch))
+		}
+	}
+	return groups
+}
+
+// Liberties returns the liberties of the group with a stone at p, or nil if there is no stone at p
+func (a *Analysis) Liberties(p Position) []Position {
+	if !a.board.rangeCheck(p) {
+		return nil
+	}
+	ch := a.chains[p.X*a.size+p.Y]
+	if ch == nil {
+		return nil
+	}
+	return a.positions(ch.liberties.indexes())
+}
+
+// InAtari returns the groups of color c with a single liberty
+func (a *Analysis) InAtari(c Color) []Group {
+	var groups []Group
+	for _, g := range a.Groups() {
+		if g.Color == c && g.InAtari() {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// Apply adds the move and returns the number of captured pieces, as Board.Apply does,
+// updating only the groups next to the move. Like Board.Apply it does not check for ko.
+func (a *Analysis) Apply(m Move) (int, error) {
+	if err := a.board.valid(m); err != nil {
+		return 0, err
+	}
+	if err := a.board.intersectionEmpty(m.Position); err != nil {
+		return 0, ErrSpotNotEmpty
+	}
+	i := m.X*a.size + m.Y
+	adjacent := a.adjacent(i)
+
+	// The stone is only captured itself if it neither has a liberty, nor takes the last
+	// liberty of an opponent's group, nor joins a group with a liberty to spare
+	suicide := true
+	for _, adj := range adjacent {
+		switch {
+		case adj < 0:
+			continue
+		case a.chains[adj] == nil:
+			suicide = false
+		case a.chains[adj].color == m.Player:
+			if a.chains[adj].liberties.count() > 1 {
+				suicide = false
+			}
+		default:
+			if a.chains[adj].liberties.count() == 1 {
+				suicide = false
+			}
+		}
+	}
+	if suicide {
+		return 0, ErrSelfCapture
+	}
+
+	a.board.set(m.Position, m.Player)
+	ch := &chain{color: m.Player, stones: []int{i}, liberties: newPoints(a.size)}
+	a.chains[i] = ch
+	for _, adj := range adjacent {
+		switch {
+		case adj < 0:
+		case a.chains[adj] == nil:
+			ch.liberties.add(adj)
+		case a.chains[adj].color == m.Player && a.chains[adj] != ch:
+			ch = a.merge(ch, a.chains[adj])
+		}
+	}
+	ch.liberties.remove(i)
+
+	captured := 0
+	for _, adj := range adjacent {
+		if adj < 0 || a.chains[adj] == nil || a.chains[adj].color == m.Player {
+			continue
+		}
+		opponent := a.chains[adj]
+		opponent.liberties.remove(i)
+		if opponent.liberties.count() == 0 {
+			captured += a.capture(opponent)
+		}
+	}
+	return captured, nil
+}
+
+// merge joins two chains of the same color, keeping the larger
+func (a *Analysis) merge(ch, other *chain) *chain {
+	if len(ch.stones) < len(other.stones) {
+		ch, other = other, ch
+	}
+	for _, s := range other.stones {
+		a.chains[s] = ch
+	}
+	ch.stones = append(ch.stones, other.stones...)
+	ch.liberties.union(other.liberties)
+	return ch
+}
+
+// capture clears the chain's stones from the board, giving their points to the groups around them
+func (a *Analysis) capture(ch *chain) int {
+	stones := a.board.slice()
+	for _, s := range ch.stones {
+		stones[s] = empty
+		a.chains[s] = nil
+	}
+	for _, s := range ch.stones {
+		for _, adj := range a.adjacent(s) {
+			if adj >= 0 && a.chains[adj] != nil {
+				a.chains[adj].liberties.add(s)
+			}
+		}
+	}
+	return len(ch.stones)
+}
@@ -0,0 +1,240 @@
+package game
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// checkAnalysis compares the groups of a with those found on its board from scratch
+func checkAnalysis(t *testing.T, a *Analysis) {
+	if expected, got := a.Board().Analyze().Groups(), a.Groups(); !reflect.DeepEqual(expected, got) {
+		t.Fatalf("groups out of date, expected %+v, got %+v\n%s", expected, got, a.Board())
+	}
+}
+
+func TestGroups(t *testing.T) {
+	b := sliceBoard([]Color{
+		Black, empty, White,
+		Black, empty, White,
+		empty, White, empty,
+	}, 3)
+	expected := []Group{
+		{Black, []Position{{0, 0}, {1, 0}}, []Position{{0, 1}, {1, 1}, {2, 0}}},
+		{White, []Position{{0, 2}, {1, 2}}, []Position{{0, 1}, {1, 1}, {2, 2}}},
+		{White, []Position{{2, 1}}, []Position{{1, 1}, {2, 0}, {2, 2}}},
+	}
+	if g := b.Groups(); !reflect.DeepEqual(g, expected) {
+		t.Errorf("expected %+v, got %+v", expected, g)
+	}
+	if l := b.Liberties(Position{1, 0}); !reflect.DeepEqual(l, expected[0].Liberties) {
+		t.Errorf("expected %v, got %v", expected[0].Liberties, l)
+	}
+	for _, p := range []Position{{1, 1}, {3, 0}, {-1, 0}} {
+		if l := b.Liberties(p); l != nil {
+			t.Errorf("expected no liberties at %v, got %v", p, l)
+		}
+	}
+	if g := b.InAtari(Black); g != nil {
+		t.Errorf("expected no groups in atari, got %+v", g)
+	}
+	if b := newBoard(5); b.Groups() != nil {
+		t.Error("expected no groups on an empty board")
+	}
+}
+
+// analysisMove is a move applied to an Analysis, with the stones it captures
+// and the liberties of the group it was played in afterwards
+type analysisMove struct {
+	Move
+	captured  int
+	liberties []Position
+}
+
+func TestAnalysisApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		initial []Color
+		moves   []analysisMove
+		atari   []Group // of the last player's opponent, after the moves
+	}{
+		{
+			// Black throws in at the corner, White takes the stone and Black takes back five
+			"snapback", 5,
+			[]Color{
+				empty, empty, White, Black, empty,
+				White, White, White, Black, empty,
+				Black, Black, Black, empty, empty,
+				empty, empty, empty, empty, empty,
+				empty, empty, empty, empty, empty,
+			},
+			[]analysisMove{
+				{Move{Black, Position{0, 0}}, 0, []Position{{0, 1}}},
+				{Move{White, Position{0, 1}}, 1, []Position{{0, 0}}},
+				{Move{Black, Position{0, 0}}, 5, []Position{{0, 1}, {1, 0}}},
+			},
+			nil,
+		},
+		{
+			"shared liberties", 3,
+			[]Color{
+				Black, empty, White,
+				Black, empty, White,
+				empty, empty, empty,
+			},
+			[]analysisMove{
+				{Move{Black, Position{0, 1}}, 0, []Position{{1, 1}, {2, 0}}},
+				{Move{White, Position{1, 1}}, 0, []Position{{2, 1}, {2, 2}}},
+			},
+			[]Group{{Black, []Position{{0, 0}, {0, 1}, {1, 0}}, []Position{{2, 0}}}},
+		},
+		{
+			// The liberty both black stones share is only counted once
+			"joined liberty", 3,
+			[]Color{
+				Black, empty, empty,
+				empty, Black, empty,
+				empty, empty, empty,
+			},
+			[]analysisMove{
+				{Move{Black, Position{0, 1}}, 0, []Position{{0, 2}, {1, 0}, {1, 2}, {2, 1}}},
+			},
+			nil,
+		},
+		{
+			// Taking the last liberty of both white groups at once
+			"double capture", 3,
+			[]Color{
+				White, empty, White,
+				Black, empty, Black,
+				empty, empty, empty,
+			},
+			[]analysisMove{
+				{Move{Black, Position{0, 1}}, 2, []Position{{0, 0}, {0, 2}, {1, 1}}},
+			},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		a := sliceBoard(test.initial, test.size).Analyze()
+		for i, m := range test.moves {
+			captured, err := a.Apply(m.Move)
+			if err != nil || captured != m.captured {
+				t.Fatalf("%s move %d: expected %d captured, got %d '%v'", test.name, i, m.captured, captured, err)
+			}
+			if l := a.Liberties(m.Position); !reflect.DeepEqual(l, m.liberties) {
+				t.Errorf("%s move %d: expected liberties %v, got %v", test.name, i, m.liberties, l)
+			}
+			checkAnalysis(t, a)
+		}
+		last := test.moves[len(test.moves)-1].Player
+		if g := a.InAtari(last.Opponent()); !reflect.DeepEqual(g, test.atari) {
+			t.Errorf("%s: expected %+v in atari, got %+v", test.name, test.atari, g)
+		}
+	}
+}
+
+func TestAnalysisInvalid(t *testing.T) {
+	a := sliceBoard([]Color{
+		empty, Black, White,
+		Black, Black, White,
+		empty, White, empty,
+	}, 3).Analyze()
+	tests := []struct {
+		m   Move
+		err error
+	}{
+		{Move{White, Position{3, 0}}, ErrOutOfBounds},
+		{Move{White, Position{1, 1}}, ErrSpotNotEmpty},
+		{Move{White, Position{0, 0}}, ErrSelfCapture},
+	}
+	for _, test := range tests {
+		if _, err := a.Apply(test.m); err != test.err {
+			t.Errorf("%+v: expected '%s', got '%v'", test.m, test.err, err)
+		}
+	}
+	checkAnalysis(t, a)
+	// Filling its own eyes is allowed until the last one
+	if _, err := a.Apply(Move{Black, Position{0, 0}}); err != nil {
+		t.Errorf("unexpected error '%s'", err)
+	}
+	if g := a.InAtari(Black); len(g) != 1 || len(g[0].Stones) != 4 {
+		t.Errorf("expected Black to be in atari, got %+v", g)
+	}
+}
+
+// TestAnalysisRandom plays random games, checking the analysis agrees with Board.Apply
+func TestAnalysisRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		b := newBoard(9)
+		a := b.Analyze()
+		player := Black
+		for i := 0; i < 300; i++ {
+			m := Move{player, Position{r.Intn(9), r.Intn(9)}}
+			expected, expectedErr := b.Apply(m)
+			captured, err := a.Apply(m)
+			if captured != expected || err != expectedErr {
+				t.Fatalf("%+v: expected %d '%v', got %d '%v'\n%s", m, expected, expectedErr, captured, err, b)
+			}
+			if err := b.equal(a.Board()); err != nil {
+				t.Fatalf("%+v: %s", m, err)
+			}
+			checkAnalysis(t, a)
+			player = player.Opponent()
+		}
+	}
+}
+
+// randomMoves are the legal moves of a random game on a board of the given size
+func randomMoves(size, n int) []Move {
+	r := rand.New(rand.NewSource(1))
+	b := newBoard(size)
+	var moves []Move
+	player := Black
+	for len(moves) < n {
+		m := Move{player, Position{r.Intn(size), r.Intn(size)}}
+		if _, err := b.Apply(m); err == nil {
+			moves = append(moves, m)
+			player = player.Opponent()
+		}
+	}
+	return moves
+}
+
+func BenchmarkAnalysisApply(b *testing.B) {
+	moves := randomMoves(19, 200)
+	board := newBoard(19)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a := board.Analyze()
+		for _, m := range moves {
+			a.Apply(m)
+		}
+	}
+}
+
+func BenchmarkBoardApply(b *testing.B) {
+	moves := randomMoves(19, 200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board := newBoard(19)
+		for _, m := range moves {
+			board.Apply(m)
+		}
+	}
+}
+
+// BenchmarkAnalyze finds every group after each move, as a bot without Analysis would
+func BenchmarkAnalyze(b *testing.B) {
+	moves := randomMoves(19, 200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board := newBoard(19)
+		for _, m := range moves {
+			board.Apply(m)
+			board.Groups()
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Placement selects where handicap stones go
+type Placement int
+
+const (
+	// FixedPlacement puts handicap stones on the standard star points
+	FixedPlacement = Placement(iota)
+	// FreePlacement lets Black choose where to put handicap stones
+	FreePlacement
+)
+
+func (p Placement) String() string {
+	switch p {
+	case FreePlacement:
+		return "free"
+	default:
+		return "fixed"
+	}
+}
+
+func (p Placement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *Placement) UnmarshalJSON(data []byte) error {
+	switch strings.ToLower(string(data)) {
+	case `"free"`:
+		*p = FreePlacement
+	default:
+		*p = FixedPlacement
+	}
+	return nil
+}
+
+// Handicap gives Black stones on the board before White makes the first move
+type Handicap struct {
+	Stones    int       `json:"stones"`
+	Placement Placement `json:"placement"`
+}
+
+// MaxHandicap is the most handicap stones that can be given
+const MaxHandicap = 9
+
+// starLines are the lines holding star points, low, middle and high
+var starLines = map[int][3]int{
+	9:  {2, 4, 6},
+	13: {3, 6, 9},
+	19: {3, 9, 15},
+}
+
+// FixedHandicap returns the star points used for a handicap of n stones on a size x size board
+func FixedHandicap(size, n int) ([]Position, error) {
+	lines, ok := starLines[size]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("no fixed handicap for size %d", size)
+	case n < 2 || n > MaxHandicap:
+		return nil, fmt.Errorf("fixed handicap must be 2-%d stones, not %d", MaxHandicap, n)
+	}
+	lo, mid, hi := lines[0], lines[1], lines[2]
+	corners := []Position{{lo, hi}, {hi, lo}, {lo, lo}, {hi, hi}}
+	center := Position{mid, mid}
+	sides := []Position{{lo, mid}, {hi, mid}}
+	edges := []Position{{mid, hi}, {mid, lo}}
+	switch n {
+	case 2, 3, 4:
+		return corners[:n], nil
+	case 5:
+		return append(corners, center), nil
+	case 6:
+		return append(corners, sides...), nil
+	case 7:
+		return append(append(corners, sides...), center), nil
+	case 8:
+		return append(append(corners, sides...), edges...), nil
+	default:
+		return append(append(append(corners, sides...), edges...), center), nil
+	}
+}
+
+// Check returns an error if the rules cannot be used on a size x size board
+func (r Rules) Check(size int) error {
+	// Komi can be no more than every point on the board, which also rules out NaN and infinity
+	if max := float64(size * size); math.IsNaN(r.Komi) || math.IsInf(r.Komi, 0) || math.Abs(r.Komi) > max {
+		return fmt.Errorf("komi must be between -%g and %g, not %g", max, max, r.Komi)
+	}
+	if err := r.Time.Check(); err != nil {
+		return err
+	}
+	h := r.Handicap
+	switch {
+	case h.Stones == 0:
+		return nil
+	case h.Placement == FixedPlacement:
+		_, err := FixedHandicap(size, h.Stones)
+		return err
+	case h.Stones < 2 || h.Stones > MaxHandicap || h.Stones >= size*size:
+		return fmt.Errorf("free handicap must be 2-%d stones, not %d", MaxHandicap, h.Stones)
+	}
+	return nil
+}
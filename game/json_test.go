@@ -92,7 +92,7 @@ func TestBoardMarshal(t *testing.T) {
 // One big test, for old times sake
 func TestMarshalState(t *testing.T) {
 	size := 3
-	s := New(size, 20)
+	s := MustNew(size, 20, DefaultRules)
 	initial := []Color{
 		White, Black, empty,
 		empty, White, Black,
@@ -114,9 +114,22 @@ func TestMarshalState(t *testing.T) {
 		`"currentplayer":"Black",` +
 		`"black":{"remaining":20,"captured":0},` +
 		`"white":{"remaining":19,"captured":1},` +
-		`"lastmove":{"Player":"White","X":0,"Y":2,"PiecesRemoved":1}}`
+		`"lastmove":{"Player":"White","X":0,"Y":2,"PiecesRemoved":1},` +
+		`"rules":{"komi":7.5,"scoring":"area","passstones":false,"ko":"positional","handicap":{"stones":0,"placement":"fixed"},"time":{"system":"none"}}}`
 
 	if expected != string(data) {
 		t.Fatalf("unexpected JSON from marshalled state:\nexp: %s\ngot: %s", expected, string(data))
 	}
 }
+
+func TestRulesUnmarshal(t *testing.T) {
+	var r Rules
+	if err := json.Unmarshal([]byte(`{"scoring":"Japanese","ko":"situational"}`), &r); err != nil || r.Scoring != TerritoryScoring || r.Ko != SituationalSuperko {
+		t.Errorf("unexpected rules %+v '%v'", r, err)
+	}
+	for _, rules := range []string{`{"ko":"superko"}`, `{"scoring":"stones"}`} {
+		if err := json.Unmarshal([]byte(rules), &r); err == nil {
+			t.Errorf("expected %s to be refused", rules)
+		}
+	}
+}
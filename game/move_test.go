@@ -52,7 +52,7 @@ func TestMoveValid(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		state := New(defaultBoardSize, 10)
+		state := MustNew(defaultBoardSize, 10, DefaultRules)
 		state.player = test.current
 		err := state.Move(test.input)
 		if err != test.expected {
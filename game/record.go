@@ -0,0 +1,85 @@
+package game
+
+import "fmt"
+
+// Record holds everything needed to replay a game
+type Record struct {
+	Size   int    `json:"size"`
+	Pieces int    `json:"pieces"`
+	Rules  Rules  `json:"rules"`
+	Setup  []Move `json:"setup,omitempty"`
+	Turns  []Turn `json:"turns"`
+}
+
+// Record returns the setup and history of the game
+func (s *State) Record() Record {
+	return Record{
+		Size:   s.size,
+		Pieces: s.pieces,
+		Rules:  s.rules,
+		Setup:  s.Setup(),
+		Turns:  s.History(),
+	}
+}
+
+// Replay rebuilds the state of a game after its first n turns.
+// The error identifies the first turn that could not be played.
+func Replay(r Record, n int) (*State, error) {
+	if n < 0 || n > len(r.Turns) {
+		return nil, fmt.Errorf("turn %d is out of range 0-%d", n, len(r.Turns))
+	}
+	// Handicap stones are part of the setup
+	rules := r.Rules
+	rules.Handicap = Handicap{}
+	s, err := New(r.Size, r.Pieces, rules)
+	if err != nil {
+		return nil, err
+	}
+	s.rules = r.Rules
+	black := 0
+	for _, c := range []Color{Black, White} {
+		var ps []Position
+		for _, m := range r.Setup {
+			if m.Player == c {
+				ps = append(ps, m.Position)
+			}
+		}
+		if err := s.Place(c, ps...); err != nil {
+			return nil, fmt.Errorf("setup %s: %s", c, err.Error())
+		}
+		if c == Black {
+			black = len(ps)
+		}
+	}
+	if h := r.Rules.Handicap.Stones; h > 0 {
+		if h > black {
+			s.handicap = h - black
+		} else {
+			s.SetFirst(White)
+		}
+	}
+	if len(r.Turns) > 0 {
+		s.SetFirst(r.Turns[0].Player)
+	}
+	for i, t := range r.Turns[:n] {
+		var err error
+		switch {
+		case t.Resign:
+			err = s.Resign(t.Player)
+		case t.Timeout:
+			err = s.Timeout(t.Player)
+		case t.Pass:
+			err = s.Pass(t.Player)
+			// The second of two passes ends the game
+			if err == ErrGameOver && i == len(r.Turns)-1 {
+				err = nil
+			}
+		default:
+			err = s.Move(t.Move)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("turn %d %s: %s", i+1, t.Player, err.Error())
+		}
+	}
+	return s, nil
+}
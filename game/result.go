@@ -0,0 +1,102 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Reason is why a game ended
+type Reason int
+
+const (
+	// ScoreReason ends the game by counting the board, after two passes or when both players run out of stones
+	ScoreReason = Reason(iota)
+	// ResignReason ends the game when a player resigns
+	ResignReason
+	// TimeoutReason ends the game when a player runs out of time
+	TimeoutReason
+	// ForfeitReason ends the game when a player is disqualified or abandons it
+	ForfeitReason
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ResignReason:
+		return "resignation"
+	case TimeoutReason:
+		return "timeout"
+	case ForfeitReason:
+		return "forfeit"
+	default:
+		return "score"
+	}
+}
+
+func (r Reason) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Reason) UnmarshalJSON(data []byte) error {
+	switch strings.ToLower(string(data)) {
+	case `"resignation"`:
+		*r = ResignReason
+	case `"timeout"`:
+		*r = TimeoutReason
+	case `"forfeit"`:
+		*r = ForfeitReason
+	default:
+		*r = ScoreReason
+	}
+	return nil
+}
+
+// Result is how a game ended. Score is only set when the board was counted.
+type Result struct {
+	Winner Color  `json:"winner"`
+	Reason Reason `json:"reason"`
+	Score  *Score `json:"score,omitempty"`
+}
+
+// Result returns how the game ended, and false while it is still being played
+func (s *State) Result() (Result, bool) {
+	switch {
+	case !s.over:
+		return Result{}, false
+	case s.result != nil:
+		return *s.result, true
+	}
+	score := s.Score()
+	return Result{Winner: score.Winner, Reason: ScoreReason, Score: &score}, true
+}
+
+// Resign ends the game with a win for c's opponent. Players can resign at any time.
+func (s *State) Resign(c Color) error {
+	if s.over {
+		return ErrGameOver
+	}
+	if c != Black && c != White {
+		return ErrWrongPlayer
+	}
+	s.turns = append(s.turns, Turn{Move: Move{Player: c}, Resign: true})
+	s.end(c.Opponent(), ResignReason)
+	return nil
+}
+
+// Timeout ends the game with a win for c's opponent when c runs out of time
+func (s *State) Timeout(c Color) error {
+	if s.over {
+		return ErrGameOver
+	}
+	if c != Black && c != White {
+		return ErrWrongPlayer
+	}
+	s.turns = append(s.turns, Turn{Move: Move{Player: c}, Timeout: true})
+	s.end(c.Opponent(), TimeoutReason)
+	return nil
+}
+
+// end finishes the game without counting the board
+func (s *State) end(winner Color, reason Reason) {
+	s.over = true
+	s.result = &Result{Winner: winner, Reason: reason}
+}
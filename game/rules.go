@@ -0,0 +1,150 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Scoring selects how points are counted at the end of a game
+type Scoring int
+
+const (
+	// AreaScoring counts stones on the board plus surrounded territory (Chinese)
+	AreaScoring = Scoring(iota)
+	// TerritoryScoring counts surrounded territory plus captured stones (Japanese/AGA)
+	TerritoryScoring
+)
+
+func (s Scoring) String() string {
+	switch s {
+	case TerritoryScoring:
+		return "territory"
+	default:
+		return "area"
+	}
+}
+
+func (s Scoring) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Scoring) UnmarshalJSON(data []byte) error {
+	switch strings.ToLower(string(data)) {
+	case `"territory"`, `"japanese"`, `"aga"`:
+		*s = TerritoryScoring
+	case `"area"`, `"chinese"`:
+		*s = AreaScoring
+	default:
+		return fmt.Errorf("%s is not a valid scoring", data)
+	}
+	return nil
+}
+
+// KoRule selects which repeated positions are forbidden
+type KoRule int
+
+const (
+	// SimpleKo forbids recreating the position before the opponent's last move
+	SimpleKo = KoRule(iota)
+	// PositionalSuperko forbids recreating any earlier board
+	PositionalSuperko
+	// SituationalSuperko forbids recreating any earlier board with the same player to move
+	SituationalSuperko
+)
+
+func (k KoRule) String() string {
+	switch k {
+	case PositionalSuperko:
+		return "positional"
+	case SituationalSuperko:
+		return "situational"
+	default:
+		return "simple"
+	}
+}
+
+func (k KoRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+func (k *KoRule) UnmarshalJSON(data []byte) error {
+	switch strings.ToLower(string(data)) {
+	case `"positional"`:
+		*k = PositionalSuperko
+	case `"situational"`:
+		*k = SituationalSuperko
+	case `"simple"`:
+		*k = SimpleKo
+	default:
+		return fmt.Errorf("%s is not a valid ko rule", data)
+	}
+	return nil
+}
+
+// Rules configures how a game is played and scored
+type Rules struct {
+	// Komi is added to White's score to offset Black's first move
+	Komi float64 `json:"komi"`
+	// Scoring is the method used to count points
+	Scoring Scoring `json:"scoring"`
+	// PassStones gives the opponent a captured stone for every pass (AGA)
+	PassStones bool `json:"passstones"`
+	// Ko is the rule used to reject repeated positions
+	Ko KoRule `json:"ko"`
+	// Handicap stones are given to Black, and White moves first
+	Handicap Handicap `json:"handicap"`
+	// Time is the time control the server enforces for each player
+	Time TimeControl `json:"time"`
+}
+
+// DefaultRules are area scoring with a half point komi so games cannot tie,
+// and positional superko so games cannot loop
+var DefaultRules = Rules{
+	Komi:    7.5,
+	Scoring: AreaScoring,
+	Ko:      PositionalSuperko,
+}
+
+// Points is the breakdown of one player's score
+type Points struct {
+	Stones    int     `json:"stones"`
+	Territory int     `json:"territory"`
+	Captured  int     `json:"captured"`
+	Komi      float64 `json:"komi"`
+	Total     float64 `json:"total"`
+}
+
+// Score is the result of counting a board. Margin is positive if Black leads.
+type Score struct {
+	Black  Points  `json:"black"`
+	White  Points  `json:"white"`
+	Margin float64 `json:"margin"`
+	Winner Color   `json:"winner"`
+}
+
+// Score counts the board b under the rules, given each player's stone counts
+func (r Rules) Score(b Board, black, white Stones) Score {
+	var s Score
+	s.Black.Stones, s.White.Stones, s.Black.Territory, s.White.Territory = b.count()
+	s.Black.Captured = black.Captured
+	s.White.Captured = white.Captured
+	s.White.Komi = r.Komi
+	for _, p := range []*Points{&s.Black, &s.White} {
+		p.Total = float64(p.Territory) + p.Komi
+		switch r.Scoring {
+		case TerritoryScoring:
+			p.Total += float64(p.Captured)
+		default:
+			p.Total += float64(p.Stones)
+		}
+	}
+	s.Margin = s.Black.Total - s.White.Total
+	switch {
+	case s.Margin > 0:
+		s.Winner = Black
+	case s.Margin < 0:
+		s.Winner = White
+	}
+	return s
+}
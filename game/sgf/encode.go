@@ -0,0 +1,85 @@
+package sgf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// Encode writes the record as a single SGF game tree
+func (r *Record) Encode(w io.Writer) error {
+	_, err := io.WriteString(w, r.String())
+	return err
+}
+
+// String returns the record as a single SGF game tree
+func (r *Record) String() string {
+	var b bytes.Buffer
+	b.WriteString("(;FF[4]GM[1]CA[UTF-8]")
+	fmt.Fprintf(&b, "SZ[%d]", r.Size)
+	b.WriteString("KM[" + strconv.FormatFloat(r.Komi, 'f', -1, 64) + "]")
+	property(&b, "RU", r.Rules)
+	property(&b, "PB", r.Black)
+	property(&b, "PW", r.White)
+	property(&b, "RE", r.Result)
+	var black, white []game.Move
+	for _, m := range r.Setup {
+		if m.Player == game.Black {
+			black = append(black, m)
+		} else {
+			white = append(white, m)
+		}
+	}
+	if len(black) > 0 && len(white) == 0 {
+		fmt.Fprintf(&b, "HA[%d]", len(black))
+	}
+	for _, setup := range []struct {
+		id    string
+		moves []game.Move
+	}{{"AB", black}, {"AW", white}} {
+		if len(setup.moves) == 0 {
+			continue
+		}
+		b.WriteString(setup.id)
+		for _, m := range setup.moves {
+			b.WriteString("[" + coordinate(m.Position) + "]")
+		}
+	}
+	property(&b, "C", r.Comment)
+	for _, n := range r.Moves {
+		b.WriteString("\n;")
+		id := "B"
+		if n.Player == game.White {
+			id = "W"
+		}
+		if n.Pass {
+			b.WriteString(id + "[]")
+		} else {
+			b.WriteString(id + "[" + coordinate(n.Position) + "]")
+		}
+		property(&b, "C", n.Comment)
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// coordinate formats a position as column then row, "aa" being the top left
+func coordinate(p game.Position) string {
+	return string(letters[p.X]) + string(letters[p.Y])
+}
+
+// property writes a text property, skipping empty values
+func property(b *bytes.Buffer, id, value string) {
+	if value == "" {
+		return
+	}
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, "]", `\]`, -1)
+	b.WriteString(id + "[" + value + "]")
+}
@@ -0,0 +1,249 @@
+package sgf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+type properties map[string][]string
+
+// Parse reads the main line of the first game tree in data.
+// Only the first variation at each branch is followed.
+func Parse(data []byte) (*Record, error) {
+	p := parser{data: string(data)}
+	var nodes []properties
+	p.skipSpace()
+	if err := p.tree(&nodes, true); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("sgf: no nodes in game tree")
+	}
+	return record(nodes)
+}
+
+type parser struct {
+	data string
+	pos  int
+}
+
+func (p *parser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("sgf: offset %d: %s", p.pos, fmt.Sprintf(format, a...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.data) {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// tree reads a game tree, appending its nodes if it is on the main line
+func (p *parser) tree(nodes *[]properties, main bool) error {
+	if p.peek() != '(' {
+		return p.errorf("expected '(' got %q", p.peek())
+	}
+	p.pos++
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case ';':
+			p.pos++
+			props, err := p.node()
+			if err != nil {
+				return err
+			}
+			if main {
+				*nodes = append(*nodes, props)
+			}
+		case '(':
+			if err := p.tree(nodes, main); err != nil {
+				return err
+			}
+			main = false
+		case ')':
+			p.pos++
+			return nil
+		case 0:
+			return p.errorf("unexpected end of game tree")
+		default:
+			return p.errorf("unexpected %q", p.peek())
+		}
+	}
+}
+
+func (p *parser) node() (properties, error) {
+	props := properties{}
+	for {
+		p.skipSpace()
+		start := p.pos
+		for c := p.peek(); c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'; c = p.peek() {
+			p.pos++
+		}
+		if start == p.pos {
+			return props, nil
+		}
+		// FF[3] allowed lower case letters in identifiers, which are ignored
+		id := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' {
+				return -1
+			}
+			return r
+		}, p.data[start:p.pos])
+		p.skipSpace()
+		if p.peek() != '[' {
+			return nil, p.errorf("property %s has no value", id)
+		}
+		for p.peek() == '[' {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			props[id] = append(props[id], v)
+			p.skipSpace()
+		}
+	}
+}
+
+func (p *parser) value() (string, error) {
+	p.pos++ // '['
+	var v []byte
+	for {
+		if p.pos >= len(p.data) {
+			return "", p.errorf("unterminated property value")
+		}
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case ']':
+			return string(v), nil
+		case '\\':
+			if p.pos >= len(p.data) {
+				return "", p.errorf("unterminated property value")
+			}
+			c = p.data[p.pos]
+			p.pos++
+			// Escaped newlines are soft line breaks
+			if c != '\n' {
+				v = append(v, c)
+			}
+		default:
+			v = append(v, c)
+		}
+	}
+}
+
+func record(nodes []properties) (*Record, error) {
+	root := nodes[0]
+	r := &Record{
+		Size:    19,
+		Rules:   first(root, "RU"),
+		Black:   first(root, "PB"),
+		White:   first(root, "PW"),
+		Result:  first(root, "RE"),
+		Comment: first(root, "C"),
+	}
+	if sz := first(root, "SZ"); sz != "" {
+		size, err := strconv.Atoi(strings.SplitN(sz, ":", 2)[0])
+		if err != nil || size < 1 || size > len(letters) {
+			return nil, fmt.Errorf("sgf: invalid size %q", sz)
+		}
+		r.Size = size
+	}
+	if km := first(root, "KM"); km != "" {
+		komi, err := strconv.ParseFloat(km, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sgf: invalid komi %q", km)
+		}
+		r.Komi = komi
+	}
+	for _, setup := range []struct {
+		id string
+		game.Color
+	}{{"AB", game.Black}, {"AW", game.White}} {
+		for _, v := range root[setup.id] {
+			ps, err := points(v, r.Size)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range ps {
+				r.Setup = append(r.Setup, game.Move{Player: setup.Color, Position: p})
+			}
+		}
+	}
+	for i, n := range nodes {
+		if i > 0 && (n["AB"] != nil || n["AW"] != nil || n["AE"] != nil) {
+			return nil, fmt.Errorf("sgf: setup stones after the first move are not supported")
+		}
+		var node Node
+		switch {
+		case n["B"] != nil:
+			node.Player = game.Black
+		case n["W"] != nil:
+			node.Player = game.White
+		default:
+			continue
+		}
+		v := first(n, "B") + first(n, "W")
+		if v == "" || v == "tt" && r.Size <= 19 {
+			node.Pass = true
+		} else {
+			p, err := point(v, r.Size)
+			if err != nil {
+				return nil, err
+			}
+			node.Position = p
+		}
+		node.Comment = first(n, "C")
+		r.Moves = append(r.Moves, node)
+	}
+	return r, nil
+}
+
+func first(props properties, id string) string {
+	if len(props[id]) == 0 {
+		return ""
+	}
+	return props[id][0]
+}
+
+func point(v string, size int) (game.Position, error) {
+	if len(v) != 2 {
+		return game.Position{}, fmt.Errorf("sgf: invalid point %q", v)
+	}
+	x := strings.IndexByte(letters, v[0])
+	y := strings.IndexByte(letters, v[1])
+	if x < 0 || y < 0 || x >= size || y >= size {
+		return game.Position{}, fmt.Errorf("sgf: invalid point %q", v)
+	}
+	return game.Position{X: x, Y: y}, nil
+}
+
+// points reads a point or a compressed rectangle of points such as "aa:cc"
+func points(v string, size int) ([]game.Position, error) {
+	corners := strings.SplitN(v, ":", 2)
+	from, err := point(corners[0], size)
+	if err != nil || len(corners) == 1 {
+		return []game.Position{from}, err
+	}
+	to, err := point(corners[1], size)
+	if err != nil {
+		return nil, err
+	}
+	var ps []game.Position
+	for x := from.X; x <= to.X; x++ {
+		for y := from.Y; y <= to.Y; y++ {
+			ps = append(ps, game.Position{X: x, Y: y})
+		}
+	}
+	return ps, nil
+}
@@ -0,0 +1,120 @@
+// Package sgf reads and writes game records in the Smart Game Format (FF[4])
+package sgf
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// Node is a single move or pass in a record, with an optional comment
+type Node struct {
+	game.Turn
+	Comment string
+}
+
+// Record is the main line of a game
+type Record struct {
+	Size    int
+	Komi    float64
+	Rules   string
+	Black   string
+	White   string
+	Result  string
+	Comment string
+	// Setup stones placed before the first move (AB/AW), such as handicap stones
+	Setup []game.Move
+	Moves []Node
+}
+
+// FromState builds a record from the setup stones and history of s.
+// Player names are left for the caller to fill in.
+func FromState(s *game.State) *Record {
+	r := &Record{
+		Size:  s.Size(),
+		Komi:  s.Rules().Komi,
+		Rules: rulesName(s.Rules()),
+		Setup: s.Setup(),
+	}
+	for _, t := range s.History() {
+		// Resignation and timeout are only recorded in the result
+		if !t.Resign && !t.Timeout {
+			r.Moves = append(r.Moves, Node{Turn: t})
+		}
+	}
+	if result, ok := s.Result(); ok {
+		r.Result = Outcome(result)
+	}
+	return r
+}
+
+// Outcome formats how a game ended as an SGF result, such as "W+R" when Black resigns
+func Outcome(r game.Result) string {
+	var reason string
+	switch r.Reason {
+	case game.ScoreReason:
+		return Result(*r.Score)
+	case game.ResignReason:
+		reason = "R"
+	case game.TimeoutReason:
+		reason = "T"
+	default:
+		reason = "F"
+	}
+	switch r.Winner {
+	case game.Black:
+		return "B+" + reason
+	case game.White:
+		return "W+" + reason
+	default:
+		return "Void"
+	}
+}
+
+// Result formats a score as an SGF result, such as "B+3.5" or "0" for a draw
+func Result(s game.Score) string {
+	switch s.Winner {
+	case game.Black:
+		return "B+" + strconv.FormatFloat(s.Margin, 'f', -1, 64)
+	case game.White:
+		return "W+" + strconv.FormatFloat(-s.Margin, 'f', -1, 64)
+	default:
+		return "0"
+	}
+}
+
+func rulesName(r game.Rules) string {
+	if r.Scoring == game.TerritoryScoring {
+		return "Japanese"
+	}
+	return "Chinese"
+}
+
+// GameRules returns the rules the record was played under, starting from game.DefaultRules
+func (r *Record) GameRules() game.Rules {
+	rules := game.DefaultRules
+	rules.Komi = r.Komi
+	switch strings.ToLower(r.Rules) {
+	case "japanese", "aga", "korean":
+		rules.Scoring = game.TerritoryScoring
+	case "chinese", "nz", "":
+		rules.Scoring = game.AreaScoring
+	}
+	return rules
+}
+
+// Replay plays the record on a new game.State with pieces stones per player.
+// The error identifies the first move that could not be played.
+func (r *Record) Replay(pieces int) (*game.State, error) {
+	rec := game.Record{
+		Size:   r.Size,
+		Pieces: pieces,
+		Rules:  r.GameRules(),
+		Setup:  r.Setup,
+	}
+	for _, n := range r.Moves {
+		rec.Turns = append(rec.Turns, n.Turn)
+	}
+	return game.Replay(rec, len(rec.Turns))
+}
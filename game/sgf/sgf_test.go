@@ -0,0 +1,184 @@
+package sgf
+
+import (
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// A ko is taken, a threat answered and the ko retaken, then both players pass
+const koGame = `(;FF[4]GM[1]CA[UTF-8]SZ[5]KM[0.5]RU[Chinese]PB[random]PW[competitive]RE[B+2.5]C[Ko fight]
+;B[ba]
+;W[ca]
+;B[ab]
+;W[db]
+;B[bc]
+;W[cc]
+;B[cb]
+;W[bb]C[White takes the ko]
+;B[ee]C[Ko threat]
+;W[ed]
+;B[cb]C[Black retakes]
+;W[]
+;B[])
+`
+
+const handicapGame = `(;FF[4]GM[1]CA[UTF-8]SZ[9]KM[0.5]RU[Japanese]HA[2]AB[gc][cg]C[Two stone handicap]
+;W[ee]C[White moves first]
+;B[cc]
+;W[gg])
+`
+
+func TestRoundTrip(t *testing.T) {
+	for _, fixture := range []string{koGame, handicapGame} {
+		r, err := Parse([]byte(fixture))
+		if err != nil {
+			t.Fatalf("unable to parse fixture: '%s'", err)
+		}
+		if r.String() != fixture {
+			t.Errorf("record did not round trip:\nexp: %s\ngot: %s", fixture, r.String())
+		}
+	}
+}
+
+func TestReplayKo(t *testing.T) {
+	r, err := Parse([]byte(koGame))
+	if err != nil {
+		t.Fatalf("unable to parse fixture: '%s'", err)
+	}
+	if len(r.Moves) != 13 {
+		t.Fatalf("expected 13 moves, got %d", len(r.Moves))
+	}
+	if !r.Moves[11].Pass || !r.Moves[12].Pass {
+		t.Errorf("expected the game to end with two passes, got %+v", r.Moves[11:])
+	}
+	s, err := r.Replay(100)
+	if err != nil {
+		t.Fatalf("unable to replay fixture: '%s'", err)
+	}
+	if !s.Over() {
+		t.Error("expected game to be over after two passes")
+	}
+	history := s.History()
+	if history[7].Captured != 1 || history[10].Captured != 1 {
+		t.Errorf("expected both ko captures to be recorded, got %+v", history)
+	}
+
+	// Exporting the replayed game gives the same moves without names or comments
+	e := FromState(s)
+	e.Black, e.White, e.Comment = r.Black, r.White, r.Comment
+	for i := range e.Moves {
+		e.Moves[i].Comment = r.Moves[i].Comment
+		e.Moves[i].Captured = 0
+	}
+	if e.String() != koGame {
+		t.Errorf("exported state did not match:\nexp: %s\ngot: %s", koGame, e.String())
+	}
+}
+
+func TestReplayIllegalKo(t *testing.T) {
+	r, err := Parse([]byte(`(;SZ[5];B[ba];W[ca];B[ab];W[db];B[bc];W[cc];B[cb];W[bb];B[cb])`))
+	if err != nil {
+		t.Fatalf("unable to parse: '%s'", err)
+	}
+	if _, err := r.Replay(100); err == nil {
+		t.Error("expected immediate ko recapture to fail")
+	}
+}
+
+func TestReplayHandicap(t *testing.T) {
+	r, err := Parse([]byte(handicapGame))
+	if err != nil {
+		t.Fatalf("unable to parse fixture: '%s'", err)
+	}
+	expected := []game.Move{
+		{Player: game.Black, Position: game.Position{X: 6, Y: 2}},
+		{Player: game.Black, Position: game.Position{X: 2, Y: 6}},
+	}
+	if len(r.Setup) != len(expected) {
+		t.Fatalf("expected setup %v, got %v", expected, r.Setup)
+	}
+	for i := range expected {
+		if r.Setup[i] != expected[i] {
+			t.Errorf("expected setup %v, got %v", expected[i], r.Setup[i])
+		}
+	}
+	if r.GameRules().Scoring != game.TerritoryScoring {
+		t.Errorf("expected Japanese rules to use territory scoring")
+	}
+	s, err := r.Replay(100)
+	if err != nil {
+		t.Fatalf("unable to replay fixture: '%s'", err)
+	}
+	if len(s.Setup()) != 2 || len(s.History()) != 3 {
+		t.Errorf("expected 2 setup stones and 3 moves, got %v and %v", s.Setup(), s.History())
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		sgf  string
+		err  bool
+	}{
+		{"variations follow the main line", `(;SZ[9](;B[aa];W[bb](;B[cc])(;B[dd]))(;B[ee]))`, false},
+		{"escaped comment", `(;SZ[9]C[a \] b \\ c];B[aa])`, false},
+		{"pass as tt", `(;SZ[19];B[tt];W[aa])`, false},
+		{"compressed setup", `(;SZ[9]AB[aa:bb])`, false},
+		{"unterminated", `(;SZ[9];B[aa`, true},
+		{"out of bounds", `(;SZ[9];B[zz])`, true},
+		{"bad size", `(;SZ[x])`, true},
+		{"late setup", `(;SZ[9];B[aa]AW[bb])`, true},
+	}
+	for _, test := range tests {
+		r, err := Parse([]byte(test.sgf))
+		if (err != nil) != test.err {
+			t.Errorf("%s: unexpected error result '%v'", test.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		switch test.name {
+		case "variations follow the main line":
+			if len(r.Moves) != 3 || r.Moves[2].Position != (game.Position{X: 2, Y: 2}) {
+				t.Errorf("%s: got %+v", test.name, r.Moves)
+			}
+		case "escaped comment":
+			if r.Comment != `a ] b \ c` {
+				t.Errorf("%s: got %q", test.name, r.Comment)
+			}
+		case "pass as tt":
+			if !r.Moves[0].Pass || r.Moves[1].Pass {
+				t.Errorf("%s: got %+v", test.name, r.Moves)
+			}
+		case "compressed setup":
+			if len(r.Setup) != 4 {
+				t.Errorf("%s: got %+v", test.name, r.Setup)
+			}
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	s := game.MustNew(5, 20, game.DefaultRules)
+	s.Move(game.Move{Player: game.Black, Position: game.Position{X: 2, Y: 2}})
+	s.Resign(game.White)
+	r := FromState(s)
+	if r.Result != "B+R" || len(r.Moves) != 1 {
+		t.Errorf("expected B+R after one move, got %s after %v", r.Result, r.Moves)
+	}
+	tests := []struct {
+		result   game.Result
+		expected string
+	}{
+		{game.Result{Winner: game.White, Reason: game.TimeoutReason}, "W+T"},
+		{game.Result{Winner: game.Black, Reason: game.ForfeitReason}, "B+F"},
+		{game.Result{Reason: game.ScoreReason, Score: &game.Score{Margin: -6.5, Winner: game.White}}, "W+6.5"},
+	}
+	for _, test := range tests {
+		if o := Outcome(test.result); o != test.expected {
+			t.Errorf("expected %+v to be %s, got %s", test.result, test.expected, o)
+		}
+	}
+}
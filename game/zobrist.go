@@ -0,0 +1,49 @@
+package game
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Zobrist keys are generated from a fixed seed so hashes are stable between runs
+var zobrist = struct {
+	sync.Mutex
+	keys map[int][]uint64
+}{keys: map[int][]uint64{}}
+
+func zobristKeys(size int) []uint64 {
+	zobrist.Lock()
+	defer zobrist.Unlock()
+	keys, ok := zobrist.keys[size]
+	if !ok {
+		r := rand.New(rand.NewSource(int64(size)))
+		keys = make([]uint64, size*size*2)
+		for i := range keys {
+			keys[i] = r.Uint64()
+		}
+		zobrist.keys[size] = keys
+	}
+	return keys
+}
+
+// zobristKey returns the key for a stone of color c at index i of a board's slice
+func zobristKey(keys []uint64, i int, c Color) uint64 {
+	switch c {
+	case Black:
+		return keys[i*2]
+	case White:
+		return keys[i*2+1]
+	}
+	return 0
+}
+
+// Hash returns the Zobrist hash of the stones on the board.
+// State keeps its hash up to date as moves are applied instead of calling Hash each move.
+func (b Board) Hash() uint64 {
+	keys := zobristKeys(len(b))
+	var h uint64
+	for i, c := range b.slice() {
+		h ^= zobristKey(keys, i, c)
+	}
+	return h
+}
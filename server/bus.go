@@ -0,0 +1,218 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// EventKind is what happened to a game in a GameEvent
+type EventKind string
+
+const (
+	GameCreated  EventKind = "create"
+	PlayerJoined EventKind = "join"
+	MovePlayed   EventKind = "move"
+	Passed       EventKind = "pass"
+	Resigned     EventKind = "resign"
+	TimedOut     EventKind = "timeout"
+	// TurnUndone is the last turn being taken back
+	TurnUndone EventKind = "undo"
+	// GameEnded follows the turn that ended the game, with its result
+	GameEnded EventKind = "end"
+)
+
+// GameEvent is a change to a game. Every change is published on the api's Bus,
+// and a Store keeps them so a game can be rebuilt by replaying its events.
+type GameEvent struct {
+	Kind EventKind `json:"kind"`
+	Game uint64    `json:"game"`
+	// Settings are those of a created game
+	Settings *Settings `json:"settings,omitempty"`
+	// Player is the seat taken by a player joining as Color,
+	// who is the registered player Account, or 0 for anyone
+	Player  GameID     `json:"player,omitempty"`
+	Color   game.Color `json:"color,omitempty"`
+	Account uint64     `json:"account,omitempty"`
+	// Turn is the move, pass, resignation or timeout played
+	Turn   *game.Turn   `json:"turn,omitempty"`
+	Result *game.Result `json:"result,omitempty"`
+}
+
+// turnEvent is the event of a turn played in game n
+func turnEvent(n uint64, t game.Turn) GameEvent {
+	kind := MovePlayed
+	switch {
+	case t.Resign:
+		kind = Resigned
+	case t.Timeout:
+		kind = TimedOut
+	case t.Pass:
+		kind = Passed
+	}
+	return GameEvent{Kind: kind, Game: n, Turn: &t}
+}
+
+// apply plays the event on s, leaving it unchanged by events that do not change the board
+func (e GameEvent) apply(s *game.State) error {
+	var err error
+	switch e.Kind {
+	case MovePlayed, Passed, Resigned, TimedOut:
+		if e.Turn == nil {
+			return fmt.Errorf("game %d %s has no turn", e.Game, e.Kind)
+		}
+		t := *e.Turn
+		switch e.Kind {
+		case Resigned:
+			err = s.Resign(t.Player)
+		case TimedOut:
+			err = s.Timeout(t.Player)
+		case Passed:
+			err = s.Pass(t.Player)
+		default:
+			err = s.Move(t.Move)
+		}
+	case TurnUndone:
+		err = s.Undo()
+	}
+	// The turn that ends a game is played before the error says so
+	if err == game.ErrGameOver {
+		err = nil
+	}
+	return err
+}
+
+// Rebuild replays the events of a game, starting with its creation, to recover its state
+func Rebuild(events []GameEvent) (*game.State, error) {
+	if len(events) == 0 || events[0].Kind != GameCreated || events[0].Settings == nil {
+		return nil, fmt.Errorf("events do not start with the game being created")
+	}
+	settings := events[0].Settings
+	s, err := game.New(settings.Size, Pieces, settings.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("event 1: %s", err.Error())
+	}
+	for i, e := range events[1:] {
+		if err := e.apply(s); err != nil {
+			return nil, fmt.Errorf("event %d: %s", i+2, err.Error())
+		}
+	}
+	return s, nil
+}
+
+// Subscriber is called with every event published on a Bus, and the game it happened in
+type Subscriber func(g *Game, e GameEvent)
+
+// Bus delivers the events of every game to its subscribers, in the order they subscribed.
+// Events are delivered as they are published, and each game's events in order.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber
+}
+
+func (b *Bus) Subscribe(s Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, s)
+}
+
+// Publish calls every subscriber with e, which can publish events of their own
+func (b *Bus) Publish(g *Game, e GameEvent) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+	for _, s := range subscribers {
+		s(g, e)
+	}
+}
+
+// changed publishes the turns played in g since it last published, and the end of the game.
+// It is called after every action that can play a turn.
+func (a *api) changed(g *Game) {
+	g.changing.Lock()
+	defer g.changing.Unlock()
+	g.mu.Lock()
+	var events []GameEvent
+	h := g.state.History()
+	for _, t := range h[g.published:] {
+		events = append(events, turnEvent(g.id, t))
+	}
+	g.published = len(h)
+	result, over := g.state.Result()
+	if over && !g.ended {
+		events = append(events, GameEvent{Kind: GameEnded, Game: g.id, Result: &result})
+	}
+	g.ended = over
+	g.mu.Unlock()
+	for _, e := range events {
+		a.bus.Publish(g, e)
+	}
+}
+
+// undone publishes the last turn of g being taken back
+func (a *api) undone(g *Game) {
+	g.changing.Lock()
+	defer g.changing.Unlock()
+	g.mu.Lock()
+	g.published = len(g.state.History())
+	g.ended = g.state.Over()
+	g.mu.Unlock()
+	a.bus.Publish(g, GameEvent{Kind: TurnUndone, Game: g.id})
+}
+
+// created publishes a game being created, with its players once they have joined
+func (a *api) created(g *Game, s Settings) {
+	a.bus.Publish(g, GameEvent{Kind: GameCreated, Game: g.id, Settings: &s})
+}
+
+func (a *api) joined(g *Game, id GameID, c game.Color, account uint64) {
+	a.bus.Publish(g, GameEvent{Kind: PlayerJoined, Game: g.id, Player: id, Color: c, Account: account})
+}
+
+// The subscribers below are the api's, subscribed by newTimedAPI
+
+// persist stores the events the Store does not keep as it creates games and seats players
+func (a *api) persist(g *Game, e GameEvent) {
+	if e.Kind == GameCreated || e.Kind == PlayerJoined {
+		return
+	}
+	if err := a.store.Record(e); err != nil {
+		log.Println(err)
+	}
+}
+
+// notify pushes the events players and spectators are sent to those watching g
+func (a *api) notify(g *Game, e GameEvent) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch e.Kind {
+	case MovePlayed:
+		p := e.Turn.Position
+		g.publish(Event{Type: EventMove, Player: e.Turn.Player, Position: &p})
+	case Passed:
+		g.publish(Event{Type: EventPass, Player: e.Turn.Player})
+	case TurnUndone:
+		g.publish(a.stateEvent(g, EventUndo))
+		return
+	case GameEnded:
+		g.publish(Event{Type: EventGameOver, Result: e.Result})
+		return
+	default:
+		// Resignation and timeout are reported as the game being over
+		return
+	}
+	if clocks := a.timeLeft(g); clocks != nil {
+		g.publish(Event{Type: EventClock, Clocks: clocks})
+	}
+}
+
+// ended rates the players and records tournament results once a game has ended
+func (a *api) ended(g *Game, e GameEvent) {
+	if e.Kind != GameEnded {
+		return
+	}
+	a.rate(g)
+	a.finishPairing(g)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+func eventKinds(events []GameEvent) string {
+	kinds := make([]string, len(events))
+	for i, e := range events {
+		kinds[i] = string(e.Kind)
+	}
+	return strings.Join(kinds, " ")
+}
+
+func TestBus(t *testing.T) {
+	defer sequentialIDs()()
+	store := NewMemoryStore()
+	a := newAPI(store)
+	var published []GameEvent
+	a.bus.Subscribe(func(g *Game, e GameEvent) {
+		published = append(published, e)
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	a.startHandler(&testWriter{}, r)
+	a.startHandler(&testWriter{}, r)
+	w := &testWriter{}
+	playMove(a, w, "1", "[1,1]")
+	playMove(a, w, "2", "[2,2]")
+	playMove(a, w, "1", "[]")
+	r, _ = http.NewRequest("GET", "/2/undo", nil)
+	a.playHandler(w, r)
+	r, _ = http.NewRequest("GET", "/1/undo?answer=accept", nil)
+	a.playHandler(w, r)
+	if string(w.content) != `"undone"` {
+		t.Fatalf("expected the pass to be undone, got %s", w.content)
+	}
+	playMove(a, w, "1", "[3,3]")
+	r, _ = http.NewRequest("GET", "/2/resign", nil)
+	a.playHandler(w, r)
+
+	expected := "create join join move move pass undo move resign end"
+	if kinds := eventKinds(published); kinds != expected {
+		t.Errorf("expected %s published, got %s", expected, kinds)
+	}
+	events, _ := store.Events(1)
+	if kinds := eventKinds(events); kinds != expected {
+		t.Errorf("expected %s stored, got %s", expected, kinds)
+	}
+	if e := events[len(events)-1]; e.Result == nil || e.Result.Winner != game.Black || e.Result.Reason != game.ResignReason {
+		t.Errorf("unexpected end %+v", e)
+	}
+
+	s, err := Rebuild(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, _ := store.Lookup("1")
+	rebuilt, played := s.Public(), g.state.Public()
+	if rebuilt.Board[3][3] != game.Black || rebuilt.Board[2][2] != game.White || !s.Over() || len(s.History()) != len(g.state.History()) || rebuilt.CurrentPlayer != played.CurrentPlayer {
+		t.Errorf("expected the rebuilt game to match the game played, got %+v", rebuilt)
+	}
+	if _, err := Rebuild(events[1:]); err == nil {
+		t.Error("expected events without the game being created to be refused")
+	}
+	corrupt := *events[0].Settings
+	corrupt.Rules.Handicap = game.Handicap{Stones: 2}
+	corrupt.Size = 7
+	if _, err := Rebuild([]GameEvent{{Kind: GameCreated, Game: 1, Settings: &corrupt}}); err == nil {
+		t.Error("expected a game created with invalid settings to be refused")
+	}
+
+	if c := a.stats.snapshot(); c.Games != 1 || c.Moves != 3 || c.Passes != 1 || c.Undos != 1 || c.Finished != 1 || c.Wins["Black"] != 1 || c.Reasons["resignation"] != 1 {
+		t.Errorf("unexpected statistics %+v", c)
+	}
+}
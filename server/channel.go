@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/websocket"
+)
+
+// Command is sent over a game's channel. Players send the actions move (with a move
+// as for play/<GameID>/move), resign and undo (with an answer as for play/<GameID>/undo),
+// and anyone can chat. Each is answered by an EventReply with the same ID.
+type Command struct {
+	ID      int             `json:"id"`
+	Action  string          `json:"action"`
+	Move    json.RawMessage `json:"move,omitempty"`
+	Answer  string          `json:"answer,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// maxChat is the longest chat message, in bytes
+const maxChat = 500
+
+// Chat is kept in the game's log with its moves, so it is limited to chatBurst messages
+// in chatWindow on each connection and maxChats messages in each game
+const (
+	chatBurst  = 5
+	chatWindow = 10 * time.Second
+	maxChats   = 200
+)
+
+// chatLimit holds when the recent chat messages on a connection were sent
+type chatLimit []time.Time
+
+// allow reports whether a message can be sent at now, counting it if so
+func (l *chatLimit) allow(now time.Time) bool {
+	recent := (*l)[:0]
+	for _, t := range *l {
+		if now.Sub(t) < chatWindow {
+			recent = append(recent, t)
+		}
+	}
+	*l = recent
+	if len(recent) >= chatBurst {
+		return false
+	}
+	*l = append(recent, now)
+	return true
+}
+
+// channelHandler connects a player or spectator to the game's WebSocket channel.
+// Players are seated with id, and spectators have an empty id.
+func (a *api) channelHandler(g *Game, w http.ResponseWriter, r *http.Request, id GameID) {
+	from := "spectator"
+	if c, ok := g.color(id); ok {
+		from = c.String()
+	}
+	p, err := a.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if p != nil {
+		from = p.Name
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	events := g.subscribe()
+	defer g.unsubscribe(events)
+
+	g.mu.Lock()
+	state := a.stateEvent(g, EventState)
+	g.mu.Unlock()
+	if err := conn.WriteJSON(state); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var limit chatLimit
+		for {
+			data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var c Command
+			reply := Event{Type: EventReply, Game: g.id}
+			if err := json.Unmarshal(data, &c); err != nil {
+				reply.Error = &Error{Code: CodeBadRequest, Message: "Decode command error: " + err.Error()}
+			} else {
+				reply.ID = c.ID
+				reply.Error = a.command(g, r, id, from, &limit, c)
+			}
+			if err := conn.WriteJSON(reply); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// command carries out a Command sent over the channel, returning nil if it succeeded
+func (a *api) command(g *Game, r *http.Request, id GameID, from string, limit *chatLimit, c Command) *Error {
+	if c.Action == "chat" {
+		message := strings.TrimSpace(c.Message)
+		switch {
+		case message == "":
+			return &Error{Code: CodeBadRequest, Message: "Missing chat message"}
+		case len(message) > maxChat:
+			return &Error{Code: CodeBadRequest, Message: "Chat messages are limited to 500 bytes"}
+		case !limit.allow(a.clock.Now()):
+			e := newError(CodeRateLimited, "Chat is limited to %d messages every %s", chatBurst, chatWindow)
+			return &e
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.chats >= maxChats {
+			e := newError(CodeForbidden, "Game %d has reached its limit of %d chat messages", g.id, maxChats)
+			return &e
+		}
+		g.chats++
+		g.publish(Event{Type: EventChat, From: from, Message: message})
+		return nil
+	}
+	if _, ok := g.color(id); !ok {
+		e := newError(CodeForbidden, "Spectators can only chat")
+		return &e
+	}
+	if g.over() {
+		e := toError(game.ErrGameOver)
+		return &e
+	}
+	// Actions are carried out by the handlers that serve them over HTTP,
+	// answering with the errors of /api/v2
+	q := url.Values{}
+	if c.Answer != "" {
+		q.Set("answer", c.Answer)
+	}
+	req, err := http.NewRequest("POST", "/?"+q.Encode(), bytes.NewReader(c.Move))
+	if err != nil {
+		e := Error{Code: CodeInternal, Message: err.Error()}
+		return &e
+	}
+	req = req.WithContext(context.WithValue(r.Context(), versionKey{}, 2))
+	rw := &replyWriter{header: http.Header{}, status: http.StatusOK}
+	switch c.Action {
+	case "move":
+		a.moveHandler(g, rw, req, id)
+	case "undo":
+		a.undoHandler(g, rw, req, id)
+	case "resign":
+		a.resignHandler(g, rw, req, id)
+	default:
+		e := newError(CodeUnknownAction, "%s is not a valid command", c.Action)
+		return &e
+	}
+	if rw.status == http.StatusOK {
+		return nil
+	}
+	var e Error
+	if err := json.Unmarshal(rw.body.Bytes(), &e); err != nil {
+		e = Error{Code: CodeInternal, Message: rw.body.String()}
+	}
+	return &e
+}
+
+// replyWriter records the response of a handler carrying out a command
+type replyWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *replyWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *replyWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *replyWriter) WriteHeader(status int) {
+	w.status = status
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/websocket"
+)
+
+func dialChannel(t *testing.T, url, key string) *websocket.Conn {
+	header := http.Header{}
+	if key != "" {
+		header.Set(KeyHeader, key)
+	}
+	c, err := websocket.Dial("ws"+strings.TrimPrefix(url, "http"), header)
+	if err != nil {
+		t.Fatalf("unable to connect to %s: '%s'", url, err)
+	}
+	return c
+}
+
+// nextEvent reads events until one of type typ
+func nextEvent(t *testing.T, c *websocket.Conn, typ EventType) Event {
+	for {
+		var e Event
+		if err := c.ReadJSON(&e); err != nil {
+			t.Fatalf("waiting for %s: '%s'", typ, err)
+		}
+		if e.Type == typ {
+			return e
+		}
+	}
+}
+
+func TestChannel(t *testing.T) {
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	ann := register(t, ts.URL, "name=ann").Key
+	var b, w seated
+	getJSON(t, ts.URL+"/api/v2/game/start/?size=9", ann, &b)
+	getJSON(t, ts.URL+"/api/v2/game/start/?size=9", "", &w)
+	play := ts.URL + "/api/v2/game/play/"
+
+	if _, err := websocket.Dial("ws"+strings.TrimPrefix(play+string(b.ID)+"/ws", "http"), nil); err == nil {
+		t.Error("expected ann's seat to need her key")
+	}
+	black := dialChannel(t, play+string(b.ID)+"/ws", ann)
+	defer black.Close()
+	spectator := dialChannel(t, ts.URL+"/api/v2/games/"+strconv.FormatUint(b.Game, 10)+"/ws", "")
+	defer spectator.Close()
+	for _, c := range []*websocket.Conn{black, spectator} {
+		if e := nextEvent(t, c, EventState); e.Game != b.Game || e.State == nil || e.State.CurrentPlayer != game.Black {
+			t.Errorf("unexpected first event %+v", e)
+		}
+	}
+
+	move, _ := json.Marshal([]int{2, 3})
+	black.WriteJSON(Command{ID: 1, Action: "move", Move: move})
+	if e := nextEvent(t, spectator, EventMove); e.Player != game.Black || e.Position == nil || *e.Position != (game.Position{2, 3}) || e.Turns != 1 {
+		t.Errorf("unexpected move event %+v", e)
+	}
+	if e := nextEvent(t, black, EventReply); e.ID != 1 || e.Error != nil {
+		t.Errorf("expected the move to be played, got %+v", e)
+	}
+	black.WriteJSON(Command{ID: 2, Action: "move", Move: move})
+	if e := nextEvent(t, black, EventReply); e.ID != 2 || e.Error == nil || e.Error.Code != CodeNotYourTurn {
+		t.Errorf("expected %s, got %+v", CodeNotYourTurn, e)
+	}
+
+	spectator.WriteJSON(Command{ID: 1, Action: "resign"})
+	if e := nextEvent(t, spectator, EventReply); e.Error == nil || e.Error.Code != CodeForbidden {
+		t.Errorf("expected spectators to be refused, got %+v", e)
+	}
+	spectator.WriteJSON(Command{ID: 2, Action: "chat", Message: " go bot go "})
+	if e := nextEvent(t, black, EventChat); e.From != "spectator" || e.Message != "go bot go" {
+		t.Errorf("unexpected chat %+v", e)
+	}
+	black.WriteJSON(Command{ID: 3, Action: "chat", Message: "thanks"})
+	// Chat is sent back to whoever sent it too
+	if e := nextEvent(t, spectator, EventChat); e.From != "spectator" {
+		t.Errorf("expected the spectator's own chat, got %+v", e)
+	}
+	if e := nextEvent(t, spectator, EventChat); e.From != "ann" {
+		t.Errorf("expected chat from ann, got %+v", e)
+	}
+
+	// Moves played over HTTP are pushed too
+	var result game.Result
+	getJSON(t, play+string(w.ID)+"/resign", "", &result)
+	for _, c := range []*websocket.Conn{black, spectator} {
+		if e := nextEvent(t, c, EventGameOver); e.Result == nil || e.Result.Winner != game.Black || e.Turns != 2 {
+			t.Errorf("unexpected game over %+v", e)
+		}
+	}
+	black.WriteJSON(Command{ID: 4, Action: "move", Move: move})
+	if e := nextEvent(t, black, EventReply); e.Error == nil || e.Error.Code != CodeGameOver {
+		t.Errorf("expected %s, got %+v", CodeGameOver, e)
+	}
+}
+
+func TestChannelChatLimits(t *testing.T) {
+	store := NewMemoryStore()
+	mux := http.NewServeMux()
+	newAPI(store).routes(mux, 2)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	var b seated
+	getJSON(t, ts.URL+"/api/v2/game/start/", "", &b)
+	watch := ts.URL + "/api/v2/games/" + strconv.FormatUint(b.Game, 10) + "/ws"
+
+	spectator := dialChannel(t, watch, "")
+	defer spectator.Close()
+	for i := 1; i <= chatBurst+1; i++ {
+		spectator.WriteJSON(Command{ID: i, Action: "chat", Message: "hi"})
+		e := nextEvent(t, spectator, EventReply)
+		if i <= chatBurst && e.Error != nil {
+			t.Errorf("chat %d: unexpected error %+v", i, e.Error)
+		}
+		if i > chatBurst && (e.Error == nil || e.Error.Code != CodeRateLimited) {
+			t.Errorf("chat %d: expected %s, got %+v", i, CodeRateLimited, e)
+		}
+	}
+
+	// Another connection can chat until the game is full
+	g, _ := store.Lookup(b.ID)
+	g.mu.Lock()
+	g.chats = maxChats
+	g.mu.Unlock()
+	other := dialChannel(t, watch, "")
+	defer other.Close()
+	other.WriteJSON(Command{ID: 1, Action: "chat", Message: "hi"})
+	if e := nextEvent(t, other, EventReply); e.Error == nil || e.Error.Code != CodeForbidden {
+		t.Errorf("expected %s once the game is full, got %+v", CodeForbidden, e)
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// Clock tells the time and runs timers, letting tests control time
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is started by a Clock, as time.Timer is
+type Timer interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Clocks is the time each player has left
+type Clocks struct {
+	Black game.Clock `json:"black"`
+	White game.Clock `json:"white"`
+}
+
+func (c *Clocks) of(p game.Color) *game.Clock {
+	if p == game.Black {
+		return &c.Black
+	}
+	return &c.White
+}
+
+// The clock methods below must be called with g.mu held
+
+// startClock runs the current player's time, and ends the game if it runs out
+func (a *api) startClock(g *Game) {
+	tc := g.state.Rules().Time
+	if tc.System == game.NoTime || g.gameOver {
+		return
+	}
+	if g.clocks == nil {
+		g.clocks = &Clocks{tc.NewClock(), tc.NewClock()}
+	}
+	a.stopClock(g)
+	g.timing++
+	timing, p := g.timing, g.state.Public().CurrentPlayer
+	g.started = a.clock.Now()
+	g.timer = a.clock.AfterFunc(g.clocks.of(p).Left(tc), func() {
+		a.timeout(g, p, timing)
+	})
+}
+
+// stopClock stops the running player's time without charging them for it
+func (a *api) stopClock(g *Game) {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+}
+
+// thinking returns how long p has been thinking about their turn,
+// and ends the game if they have run out of time
+func (a *api) thinking(g *Game, p game.Color) (time.Duration, bool) {
+	if g.timer == nil || p != g.state.Public().CurrentPlayer {
+		return 0, true
+	}
+	d := a.clock.Now().Sub(g.started)
+	c, ok := g.clocks.of(p).Elapse(g.state.Rules().Time, d)
+	if !ok {
+		*g.clocks.of(p) = c
+		a.flag(g, p)
+	}
+	return d, ok
+}
+
+// moved charges p for a turn that took d and starts the next player's time
+func (a *api) moved(g *Game, p game.Color, d time.Duration) {
+	if g.timer == nil {
+		return
+	}
+	a.stopClock(g)
+	*g.clocks.of(p), _ = g.clocks.of(p).Move(g.state.Rules().Time, d)
+	a.startClock(g)
+}
+
+// flag ends the game with a loss on time for p
+func (a *api) flag(g *Game, p game.Color) {
+	a.stopClock(g)
+	g.state.Timeout(p)
+	g.gameOver = true
+	g.undo = game.None
+}
+
+// timeout is called by the timer of p's turn, unless a later turn has started
+func (a *api) timeout(g *Game, p game.Color, timing uint64) {
+	g.mu.Lock()
+	if g.gameOver || g.timing != timing {
+		g.mu.Unlock()
+		return
+	}
+	*g.clocks.of(p) = game.Clock{}
+	a.flag(g, p)
+	g.mu.Unlock()
+	a.changed(g)
+}
+
+// timeLeft returns the players' clocks, with the running player's thinking time taken off
+func (a *api) timeLeft(g *Game) *Clocks {
+	if g.clocks == nil {
+		return nil
+	}
+	c := *g.clocks
+	if g.timer != nil {
+		p := g.state.Public().CurrentPlayer
+		*c.of(p), _ = c.of(p).Elapse(g.state.Rules().Time, a.clock.Now().Sub(g.started))
+	}
+	return &c
+}
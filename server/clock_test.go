@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// fakeClock only moves when advanced, running the timers that are due
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{c, c.now.Add(d), f, false}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []func()
+	for _, t := range c.timers {
+		if !t.stopped && !t.at.After(c.now) {
+			t.stopped = true
+			due = append(due, t.f)
+		}
+	}
+	c.mu.Unlock()
+	for _, f := range due {
+		f()
+	}
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	stopped := t.stopped
+	t.stopped = true
+	return !stopped
+}
+
+func TestGameClock(t *testing.T) {
+	defer sequentialIDs()()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	a := newTimedAPI(NewMemoryStore(), clock)
+	r, _ := http.NewRequest("GET", "/?size=9&time=fischer&main=1m&increment=5", nil)
+	a.startHandler(&testWriter{}, r)
+	clock.Advance(time.Hour)
+	a.startHandler(&testWriter{}, r)
+
+	// Time only runs once both players are seated
+	w := &testWriter{}
+	clock.Advance(20 * time.Second)
+	playMove(a, w, "1", "[2,2]")
+	clock.Advance(30 * time.Second)
+	r, _ = http.NewRequest("GET", "/1/state", nil)
+	a.playHandler(w, r)
+	var s struct {
+		Clocks Clocks `json:"clocks"`
+	}
+	if err := json.Unmarshal(w.content, &s); err != nil {
+		t.Fatalf("unable to decode state %s: '%s'", string(w.content), err)
+	}
+	expected := Clocks{
+		Black: game.Clock{Main: 45 * time.Second},
+		White: game.Clock{Main: 30 * time.Second},
+	}
+	if s.Clocks != expected {
+		t.Errorf("expected clocks %+v, got %+v", expected, s.Clocks)
+	}
+
+	// White runs out of time without moving
+	clock.Advance(30 * time.Second)
+	g, _ := a.store.Lookup("1")
+	if !g.over() {
+		t.Fatal("expected White to lose on time")
+	}
+	result, _ := g.state.Result()
+	if result.Winner != game.Black || result.Reason != game.TimeoutReason {
+		t.Errorf("expected Black to win on time, got %+v", result)
+	}
+	if h := g.state.History(); len(h) != 2 || !h[1].Timeout {
+		t.Errorf("expected timeout in history, got %v", h)
+	}
+	playMove(a, w, "2", "[3,3]")
+	if !gameIsOver(w.content) {
+		t.Errorf("expected game to be over, got %s", string(w.content))
+	}
+}
+
+func TestStartTimeControl(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected game.TimeControl
+		err      bool
+	}{
+		{"", game.TimeControl{}, false},
+		{"time=absolute&main=90", game.TimeControl{System: game.Absolute, Main: 90 * time.Second}, false},
+		{"time=byoyomi&main=10m&period=30s&periods=5", game.TimeControl{System: game.ByoYomi, Main: 10 * time.Minute, Period: 30 * time.Second, Periods: 5}, false},
+		{"time=canadian&main=10m&period=5m&stones=25", game.TimeControl{System: game.Canadian, Main: 10 * time.Minute, Period: 5 * time.Minute, Stones: 25}, false},
+		{"time=hourglass&main=10m", game.TimeControl{}, true},
+		{"time=absolute&main=soon", game.TimeControl{}, true},
+		{"time=byoyomi&main=10m", game.TimeControl{}, true},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/?"+test.query, nil)
+		rules, err := parseRules(r.URL.Query(), 19)
+		if (err != nil) != test.err || (!test.err && rules.Time != test.expected) {
+			t.Errorf("%s: expected %+v error %v, got %+v '%v'", test.query, test.expected, test.err, rules.Time, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// ErrorCode identifies an error independently of its message, so bots can rely on it
+type ErrorCode string
+
+const (
+	CodeBadRequest        ErrorCode = "bad_request"
+	CodeBadCoordinates    ErrorCode = "bad_coordinates"
+	CodeBadRules          ErrorCode = "bad_rules"
+	CodeUnknownID         ErrorCode = "unknown_id"
+	CodeUnknownAction     ErrorCode = "unknown_action"
+	CodeUnknownGame       ErrorCode = "unknown_game"
+	CodeChallenge         ErrorCode = "challenge"
+	CodeUnauthorized      ErrorCode = "unauthorized"
+	CodeForbidden         ErrorCode = "forbidden"
+	CodeUnknownPlayer     ErrorCode = "unknown_player"
+	CodeNameTaken         ErrorCode = "name_taken"
+	CodeUnknownTournament ErrorCode = "unknown_tournament"
+	CodeNotYourTurn       ErrorCode = "not_your_turn"
+	CodeSpotNotEmpty      ErrorCode = "spot_not_empty"
+	CodeOutOfBounds       ErrorCode = "out_of_bounds"
+	CodeNoStones          ErrorCode = "no_stones"
+	CodeRepeatState       ErrorCode = "repeat_state"
+	CodeSelfCapture       ErrorCode = "self_capture"
+	CodeSetup             ErrorCode = "setup"
+	CodeHandicap          ErrorCode = "handicap"
+	CodeNoUndo            ErrorCode = "no_undo"
+	CodeNoUndoRequest     ErrorCode = "no_undo_request"
+	CodeGameOver          ErrorCode = "game_over"
+	CodeGameNotOver       ErrorCode = "game_not_over"
+	CodeRateLimited       ErrorCode = "rate_limited"
+	CodeInternal          ErrorCode = "internal"
+)
+
+// moveCodes are the codes of the errors returned by game.State
+var moveCodes = map[game.MoveError]ErrorCode{
+	game.ErrGameOver:     CodeGameOver,
+	game.ErrWrongPlayer:  CodeNotYourTurn,
+	game.ErrSpotNotEmpty: CodeSpotNotEmpty,
+	game.ErrOutOfBounds:  CodeOutOfBounds,
+	game.ErrNoStones:     CodeNoStones,
+	game.ErrRepeatState:  CodeRepeatState,
+	game.ErrSelfCapture:  CodeSelfCapture,
+	game.ErrSetup:        CodeSetup,
+	game.ErrNoUndo:       CodeNoUndo,
+	game.ErrHandicap:     CodeHandicap,
+}
+
+// statuses are the HTTP statuses /api/v2 sends with each code, 400 if missing
+var statuses = map[ErrorCode]int{
+	CodeUnknownID:         http.StatusNotFound,
+	CodeUnknownAction:     http.StatusNotFound,
+	CodeUnknownGame:       http.StatusNotFound,
+	CodeChallenge:         http.StatusForbidden,
+	CodeUnauthorized:      http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeUnknownPlayer:     http.StatusNotFound,
+	CodeNameTaken:         http.StatusConflict,
+	CodeUnknownTournament: http.StatusNotFound,
+	CodeNotYourTurn:       http.StatusConflict,
+	CodeSpotNotEmpty:      http.StatusUnprocessableEntity,
+	CodeOutOfBounds:       http.StatusUnprocessableEntity,
+	CodeNoStones:          http.StatusUnprocessableEntity,
+	CodeRepeatState:       http.StatusUnprocessableEntity,
+	CodeSelfCapture:       http.StatusUnprocessableEntity,
+	CodeSetup:             http.StatusConflict,
+	CodeHandicap:          http.StatusConflict,
+	CodeNoUndo:            http.StatusConflict,
+	CodeNoUndoRequest:     http.StatusConflict,
+	CodeGameOver:          http.StatusConflict,
+	CodeGameNotOver:       http.StatusConflict,
+	CodeRateLimited:       http.StatusTooManyRequests,
+	CodeInternal:          http.StatusInternalServerError,
+}
+
+// Error is the body of a failed /api/v2 request
+type Error struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the game.MoveError with the same code, if there is one
+func (e Error) Unwrap() error {
+	for err, code := range moveCodes {
+		if code == e.Code {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status is the HTTP status sent with the error
+func (e Error) Status() int {
+	if s, ok := statuses[e.Code]; ok {
+		return s
+	}
+	return http.StatusBadRequest
+}
+
+func newError(code ErrorCode, format string, a ...interface{}) Error {
+	return Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// toError gives err a code, treating errors without one as bad requests
+func toError(err error) Error {
+	switch err := err.(type) {
+	case Error:
+		return err
+	case game.RepeatError:
+		return Error{CodeRepeatState, err.Error(), map[string]int{"move": err.Move}}
+	case game.MoveError:
+		if code, ok := moveCodes[err]; ok {
+			return Error{Code: code, Message: err.Error()}
+		}
+	}
+	return Error{Code: CodeBadRequest, Message: err.Error()}
+}
+
+// versionKey is the request context key of the API version being served
+type versionKey struct{}
+
+// versioned serves h as version v of the API
+func versioned(v int, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, v)))
+	})
+}
+
+func version(r *http.Request) int {
+	if v, ok := r.Context().Value(versionKey{}).(int); ok {
+		return v
+	}
+	return 1
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// EventType is what happened in an Event
+type EventType string
+
+const (
+	// EventState carries the whole state, and is sent first on every connection
+	EventState EventType = "state"
+	EventMove  EventType = "move"
+	EventPass  EventType = "pass"
+	// EventUndo carries the state after a turn has been taken back
+	EventUndo EventType = "undo"
+	// EventClock carries both players' clocks after every turn of a timed game
+	EventClock    EventType = "clock"
+	EventChat     EventType = "chat"
+	EventGameOver EventType = "gameover"
+	// EventReply answers a Command, and is only sent to the connection that sent it
+	EventReply EventType = "reply"
+)
+
+// Event is something that happened in a game, pushed to its players and spectators
+type Event struct {
+	Type EventType `json:"type"`
+	// Seq numbers the events published in a game from 1.
+	// A state event has the number of the last event it includes.
+	Seq  int    `json:"seq"`
+	Game uint64 `json:"game"`
+	// Turns is the number of turns played once the event has happened
+	Turns    int               `json:"turns"`
+	Player   game.Color        `json:"player,omitempty"`
+	Position *game.Position    `json:"position,omitempty"`
+	State    *game.PublicState `json:"state,omitempty"`
+	Clocks   *Clocks           `json:"clocks,omitempty"`
+	Result   *game.Result      `json:"result,omitempty"`
+	// From is the registered name or color of whoever sent a chat Message, or spectator
+	From    string `json:"from,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ID is the Command a reply answers, with the Error if it failed
+	ID    int    `json:"id,omitempty"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// eventBuffer is how many events a slow connection can fall behind before it is dropped
+const eventBuffer = 64
+
+// keepAlive is how often an idle event stream sends a comment, so proxies keep it open
+const keepAlive = 30 * time.Second
+
+// The bus methods below must be called with g.mu held, except subscribe and unsubscribe
+
+// watch returns a channel receiving the game's events,
+// which is closed if the receiver falls too far behind
+func (g *Game) watch() chan Event {
+	ch := make(chan Event, eventBuffer)
+	g.watchers[ch] = true
+	return ch
+}
+
+func (g *Game) subscribe() chan Event {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.watch()
+}
+
+func (g *Game) unsubscribe(ch chan Event) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.watchers[ch] {
+		delete(g.watchers, ch)
+		close(ch)
+	}
+}
+
+// publish numbers e, adds it to the log and sends it to everyone watching g
+func (g *Game) publish(e Event) {
+	e.Seq = len(g.log) + 1
+	e.Game = g.id
+	e.Turns = len(g.state.History())
+	g.log = append(g.log, e)
+	for ch := range g.watchers {
+		select {
+		case ch <- e:
+		default:
+			delete(g.watchers, ch)
+			close(ch)
+		}
+	}
+}
+
+// since returns the events published after the event numbered seq,
+// and false if seq is not one of the game's events
+func (g *Game) since(seq int) ([]Event, bool) {
+	if seq < 0 || seq > len(g.log) {
+		return nil, false
+	}
+	return append([]Event{}, g.log[seq:]...), true
+}
+
+// stateEvent is the whole state of g
+func (a *api) stateEvent(g *Game, t EventType) Event {
+	s := g.state.Public()
+	return Event{Type: t, Seq: len(g.log), Game: g.id, Turns: len(g.state.History()), State: &s, Clocks: a.timeLeft(g), Result: s.Result}
+}
+
+// await blocks until done, which is called with g.mu held, is true after an event,
+// returning false if ctx ends first
+func (g *Game) await(ctx context.Context, done func() bool) bool {
+	events := g.subscribe()
+	defer func() { g.unsubscribe(events) }()
+	for {
+		g.mu.Lock()
+		ok := done()
+		g.mu.Unlock()
+		if ok {
+			return true
+		}
+		select {
+		case _, ok := <-events:
+			if !ok {
+				// Dropped for falling behind, which only means there is news
+				events = g.subscribe()
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// maxWait is the longest a wait request is held open
+const maxWait = 10 * time.Minute
+
+// awaitRequest blocks as await does for a wait request, which gives up after timeout=<duration>
+// or maxWait, whichever is sooner. It writes 204 No Content if the timeout passes first,
+// and returns true once done is.
+func (g *Game) awaitRequest(w http.ResponseWriter, r *http.Request, done func() bool) bool {
+	timeout := maxWait
+	if v := r.FormValue("timeout"); v != "" {
+		d, err := parseDuration(v)
+		if err != nil || d < 0 {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a valid timeout", v))
+			return false
+		}
+		if d < timeout {
+			timeout = d
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	if g.await(ctx, done) {
+		return true
+	}
+	// Nobody is left to answer once the request is cancelled
+	if r.Context().Err() == nil {
+		w.Header().Add("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return false
+}
+
+// streamHandler sends the game's events as Server-Sent Events, numbered with their Seq.
+// A new stream starts with the state, one resumed with Last-Event-ID starts with
+// the events that followed, and both end with the game over event.
+func (a *api) streamHandler(g *Game, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, Error{Code: CodeInternal, Message: "Streaming is not supported"})
+		return
+	}
+	resume := -1
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a valid event ID", v))
+			return
+		}
+		resume = n
+	}
+
+	g.mu.Lock()
+	events := g.watch()
+	backlog, ok := g.since(resume)
+	if !ok {
+		// The stream starts over when the events missed are not known
+		backlog = []Event{a.stateEvent(g, EventState)}
+	}
+	done := len(backlog) == 0 && g.state.Over()
+	g.mu.Unlock()
+	defer g.unsubscribe(events)
+	if done {
+		// The client has seen the game end, and 204 stops it reconnecting
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	for _, e := range backlog {
+		if writeEvent(w, e) != nil || ends(e) {
+			return
+		}
+	}
+	flusher.Flush()
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				// The client resumes from the last event it received
+				return
+			}
+			if writeEvent(w, e) != nil || ends(e) {
+				flusher.Flush()
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// ends reports whether e is the last event of a stream
+func ends(e Event) bool {
+	return e.Type == EventGameOver || e.Type == EventState && e.Result != nil
+}
+
+func writeEvent(w http.ResponseWriter, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, b)
+	return err
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// stream opens the event stream at url, resuming after the event lastID if it is set
+func stream(t *testing.T, url, lastID string) *http.Response {
+	r, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		r.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// readEvent reads the next event of a stream with its id, skipping comments,
+// and false at the end of the stream
+func readEvent(t *testing.T, r *bufio.Reader) (string, Event, bool) {
+	var id string
+	var e Event
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			return id, e, false
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && id != "":
+			return id, e, true
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	var b, w seated
+	getJSON(t, ts.URL+"/api/v2/game/start/", "", &b)
+	getJSON(t, ts.URL+"/api/v2/game/start/", "", &w)
+	play := ts.URL + "/api/v2/game/play/"
+	move := func(id GameID, m string) {
+		resp, err := http.Post(play+string(id)+"/move", "application/json", bytes.NewBufferString(m))
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("unable to move %s: %v %v", m, resp, err)
+		}
+		resp.Body.Close()
+	}
+
+	resp := stream(t, play+string(b.ID)+"/events", "")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	events := bufio.NewReader(resp.Body)
+	if id, e, _ := readEvent(t, events); id != "0" || e.Type != EventState || e.State.CurrentPlayer != game.Black {
+		t.Errorf("expected the state first, got %s %+v", id, e)
+	}
+	move(b.ID, "[3, 3]")
+	if id, e, _ := readEvent(t, events); id != "1" || e.Type != EventMove || *e.Position != (game.Position{3, 3}) {
+		t.Errorf("unexpected event %s %+v", id, e)
+	}
+	resp.Body.Close()
+
+	// Events missed while disconnected are sent on resuming
+	move(w.ID, "[4, 4]")
+	move(b.ID, "[]")
+	resp = stream(t, ts.URL+"/api/v2/games/1/events", "1")
+	events = bufio.NewReader(resp.Body)
+	for _, expected := range []Event{{Type: EventMove, Seq: 2, Player: game.White}, {Type: EventPass, Seq: 3, Player: game.Black}} {
+		if _, e, _ := readEvent(t, events); e.Type != expected.Type || e.Seq != expected.Seq || e.Player != expected.Player {
+			t.Errorf("expected %+v, got %+v", expected, e)
+		}
+	}
+	getJSON(t, play+string(w.ID)+"/resign", "", &game.Result{})
+	if id, e, _ := readEvent(t, events); id != "4" || e.Type != EventGameOver || e.Result.Winner != game.Black {
+		t.Errorf("expected the game to end, got %s %+v", id, e)
+	}
+	if _, e, ok := readEvent(t, events); ok {
+		t.Errorf("expected the stream to end with the game, got %+v", e)
+	}
+	resp.Body.Close()
+
+	// Once the client has seen the end there is nothing more to send
+	if resp = stream(t, play+string(b.ID)+"/events", "4"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204 resuming a finished stream, got %s", resp.Status)
+	}
+	resp = stream(t, play+string(b.ID)+"/events", "")
+	events = bufio.NewReader(resp.Body)
+	if id, e, _ := readEvent(t, events); id != "4" || e.Type != EventState || e.Result == nil {
+		t.Errorf("expected the final state, got %s %+v", id, e)
+	}
+	if _, _, ok := readEvent(t, events); ok {
+		t.Error("expected the stream of a finished game to end")
+	}
+	resp.Body.Close()
+	// Unknown events start the stream over
+	resp = stream(t, play+string(b.ID)+"/events", "99")
+	if _, e, _ := readEvent(t, bufio.NewReader(resp.Body)); e.Type != EventState {
+		t.Errorf("expected the state, got %+v", e)
+	}
+	resp.Body.Close()
+	var e Error
+	resp = stream(t, play+string(b.ID)+"/events", "x")
+	if json.NewDecoder(resp.Body).Decode(&e); resp.StatusCode != http.StatusBadRequest || e.Code != CodeBadRequest {
+		t.Errorf("expected a bad event ID to be refused, got %s %+v", resp.Status, e)
+	}
+	resp.Body.Close()
+}
+
+func TestWaitCancel(t *testing.T) {
+	defer sequentialIDs()()
+	a := newAPI(NewMemoryStore())
+	r, _ := http.NewRequest("GET", "/", nil)
+	a.startHandler(&testWriter{}, r)
+	a.startHandler(&testWriter{}, r)
+	g, _ := a.store.Lookup("2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, _ = http.NewRequest("GET", "/2/wait", nil)
+	done := make(chan struct{})
+	go func() {
+		a.playHandler(httptest.NewRecorder(), r.WithContext(ctx))
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a cancelled wait to return")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.watchers) != 0 {
+		t.Errorf("expected the wait to stop watching the game, %d watchers left", len(g.watchers))
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	var b, w seated
+	getJSON(t, ts.URL+"/api/v2/game/start/", "", &b)
+	getJSON(t, ts.URL+"/api/v2/game/start/", "", &w)
+	play := ts.URL + "/api/v2/game/play/"
+	wait := func(id GameID, query string) (int, string) {
+		resp, err := http.Get(play + string(id) + "/wait?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	tests := []struct {
+		id     GameID
+		query  string
+		status int
+	}{
+		{b.ID, "", http.StatusOK},
+		{w.ID, "timeout=0.05", http.StatusNoContent},
+		// Nothing has been played since the start
+		{b.ID, "since=0&timeout=50ms", http.StatusNoContent},
+		{w.ID, "since=x", http.StatusBadRequest},
+		{w.ID, "timeout=soon", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if status, body := wait(test.id, test.query); status != test.status {
+			t.Errorf("%s?%s: expected %d, got %d %s", test.id, test.query, test.status, status, body)
+		}
+	}
+
+	resp, _ := http.Post(play+string(b.ID)+"/move", "application/json", bytes.NewBufferString("[3, 3]"))
+	resp.Body.Close()
+	// The position has advanced, so Black is answered at once though it is not their turn
+	start := time.Now()
+	if status, body := wait(b.ID, "since=0"); status != http.StatusOK || body != `"go bot go"` || time.Since(start) > time.Second {
+		t.Errorf("expected wait since 0 to return at once, got %d %s", status, body)
+	}
+	if status, _ := wait(b.ID, "since=1&timeout=10ms"); status != http.StatusNoContent {
+		t.Errorf("expected Black to wait for White, got %d", status)
+	}
+	// Spectators give up the same way
+	if resp, _ := http.Get(ts.URL + "/api/v2/games/1/wait?turns=1&timeout=10ms"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected the spectator to give up, got %s", resp.Status)
+	}
+}
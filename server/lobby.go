@@ -0,0 +1,227 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// OpenGame is a game in the lobby, waiting for an opponent to play White
+type OpenGame struct {
+	game *Game
+	// account is the registered player waiting, or 0
+	account uint64
+	Game    uint64 `json:"game"`
+	// Creator is the registered player waiting to play Black
+	Creator string `json:"creator,omitempty"`
+	Listing
+	Settings
+}
+
+// Listing is how an open game is shown in the lobby, which the Store keeps with the game
+// so the game is listed the same way after a restart
+type Listing struct {
+	Name string `json:"name,omitempty"`
+	// Opponent is the only registered player who can join a challenge
+	Opponent string `json:"opponent,omitempty"`
+}
+
+// seated is sent to a player given a seat in a game
+type seated struct {
+	ID    GameID     `json:"ID"`
+	Color game.Color `json:"color"`
+	Game  uint64     `json:"game,omitempty"`
+}
+
+// The lobby methods below must be called with a.mu held
+
+// open creates a game for the lobby and seats its creator as Black
+func (a *api) open(o *OpenGame) (GameID, error) {
+	g, err := a.store.Create(o.Settings, o.Listing)
+	if err != nil {
+		return "", err
+	}
+	a.created(g, o.Settings)
+	id, err := a.store.Join(g, game.Black, o.account)
+	if err != nil {
+		return "", err
+	}
+	a.joined(g, id, game.Black, o.account)
+	o.game, o.Game = g, g.id
+	a.lobby = append(a.lobby, o)
+	return id, nil
+}
+
+// join seats account as White in an open game, taking it out of the lobby
+func (a *api) join(o *OpenGame, account uint64) (GameID, error) {
+	id, err := a.store.Join(o.game, game.White, account)
+	if err != nil {
+		return "", err
+	}
+	a.joined(o.game, id, game.White, account)
+	for i := range a.lobby {
+		if a.lobby[i] == o {
+			a.lobby = append(a.lobby[:i], a.lobby[i+1:]...)
+			break
+		}
+	}
+	o.game.mu.Lock()
+	a.startClock(o.game)
+	o.game.mu.Unlock()
+	return id, nil
+}
+
+// match returns the oldest open game with settings s that anyone can join,
+// other than a game account is already waiting in
+func (a *api) match(s Settings, account uint64) *OpenGame {
+	for _, o := range a.lobby {
+		if o.Opponent == "" && o.Settings == s && (account == 0 || o.account != account) {
+			return o
+		}
+	}
+	return nil
+}
+
+// startHandler seats the player in the oldest open game with the same settings,
+// or opens a new game for the next player asking for those settings
+func (a *api) startHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s, err := parseSettings(r.Form)
+	if err != nil {
+		writeError(w, r, Error{Code: CodeBadRules, Message: err.Error()})
+		return
+	}
+	p, err := a.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if s.Rated && p == nil {
+		writeError(w, r, errUnrated)
+		return
+	}
+	o := &OpenGame{Settings: s}
+	if p != nil {
+		o.account, o.Creator = p.ID, p.Name
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var id GameID
+	c, n := game.White, uint64(0)
+	if open := a.match(s, o.account); open != nil {
+		id, err = a.join(open, o.account)
+		n = open.Game
+	} else {
+		id, err = a.open(o)
+		c, n = game.Black, o.Game
+	}
+	if err != nil {
+		writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+		return
+	}
+	writeJSON(w, seated{id, c, n})
+}
+
+var errUnrated = newError(CodeUnauthorized, "Rated games are between registered players, send an API key in %s", KeyHeader)
+
+// lobbyHandler lists the open games at lobby/, and opens and joins them with
+// lobby/create, lobby/challenge?opponent=<name> and lobby/join/<game>.
+// Challenges are between registered players, and only shown to the player challenged.
+func (a *api) lobbyHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p, err := a.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var player string
+	var account uint64
+	if p != nil {
+		player, account = p.Name, p.ID
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch parts[0] {
+	case "":
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		open := []*OpenGame{}
+		for _, o := range a.lobby {
+			if o.Opponent == "" || (p != nil && o.Opponent == player) {
+				open = append(open, o)
+			}
+		}
+		writeJSON(w, open)
+	case "create", "challenge":
+		s, err := parseSettings(r.Form)
+		if err != nil {
+			writeError(w, r, Error{Code: CodeBadRules, Message: err.Error()})
+			return
+		}
+		if s.Rated && p == nil {
+			writeError(w, r, errUnrated)
+			return
+		}
+		o := &OpenGame{account: account, Creator: player, Listing: Listing{Name: r.FormValue("name")}, Settings: s}
+		if parts[0] == "challenge" {
+			if p == nil {
+				writeError(w, r, newError(CodeUnauthorized, "Only registered players can challenge, send an API key in %s", KeyHeader))
+				return
+			}
+			o.Opponent = r.FormValue("opponent")
+			if o.Opponent == player {
+				writeError(w, r, newError(CodeBadRequest, "Players cannot challenge themselves"))
+				return
+			}
+			if _, err := a.store.PlayerNamed(o.Opponent); err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		id, err := a.open(o)
+		if err != nil {
+			writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+			return
+		}
+		writeJSON(w, seated{id, game.Black, o.Game})
+	case "join":
+		if len(parts) != 2 {
+			writeError(w, r, newError(CodeUnknownGame, "Missing game to join"))
+			return
+		}
+		n, _ := strconv.ParseUint(parts[1], 10, 64)
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		var o *OpenGame
+		for _, open := range a.lobby {
+			if open.Game == n {
+				o = open
+			}
+		}
+		switch {
+		case o == nil:
+			writeError(w, r, newError(CodeUnknownGame, "Game %s is not open", parts[1]))
+			return
+		case o.Opponent != "" && (p == nil || o.Opponent != player):
+			writeError(w, r, newError(CodeChallenge, "Game %d is a challenge to %s", o.Game, o.Opponent))
+			return
+		case o.Rated && p == nil:
+			writeError(w, r, errUnrated)
+			return
+		case account != 0 && o.account == account:
+			writeError(w, r, newError(CodeBadRequest, "Players cannot join their own game"))
+			return
+		}
+		id, err := a.join(o, account)
+		if err != nil {
+			writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+			return
+		}
+		writeJSON(w, seated{id, game.White, o.Game})
+	default:
+		writeError(w, r, newError(CodeUnknownAction, "%s is not a valid lobby action", parts[0]))
+	}
+}
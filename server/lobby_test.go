@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// getJSON decodes the response to a request sent with key, returning its status
+func getJSON(t *testing.T, url, key string, v interface{}) int {
+	r, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		r.Header.Set(KeyHeader, key)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	json.NewDecoder(resp.Body).Decode(v)
+	return resp.StatusCode
+}
+
+func TestStartPairing(t *testing.T) {
+	defer sequentialIDs()()
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	key := register(t, ts.URL, "name=ann").Key
+	tests := []struct {
+		query    string
+		key      string
+		expected seated
+	}{
+		{"size=9", "", seated{"1", game.Black, 1}},
+		{"size=13", "", seated{"2", game.Black, 2}},
+		{"size=9&komi=0.5", "", seated{"3", game.Black, 3}},
+		{"size=9&rated=true", key, seated{"4", game.Black, 4}},
+		{"size=9&time=absolute&main=60", "", seated{"5", game.Black, 5}},
+		{"size=13", "", seated{"6", game.White, 2}},
+		{"size=9&time=absolute&main=1m", "", seated{"7", game.White, 5}},
+		{"size=9", "", seated{"8", game.White, 1}},
+	}
+	for _, test := range tests {
+		var s seated
+		getJSON(t, ts.URL+"/api/v2/game/start/?"+test.query, test.key, &s)
+		if s != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.query, test.expected, s)
+		}
+	}
+	var e Error
+	if status := getJSON(t, ts.URL+"/api/v2/game/start/?size=9&rated=true", "", &e); status != http.StatusUnauthorized || e.Code != CodeUnauthorized {
+		t.Errorf("expected anonymous rated game to be refused, got %d %+v", status, e)
+	}
+	// Komi has to be a number a score can be counted with
+	for _, komi := range []string{"NaN", "Inf", "-Inf", "1e308", "82"} {
+		e = Error{}
+		if status := getJSON(t, ts.URL+"/api/v2/game/start/?size=9&komi="+komi, "", &e); status != http.StatusBadRequest || e.Code != CodeBadRules {
+			t.Errorf("expected komi %s to be refused, got %d %+v", komi, status, e)
+		}
+	}
+}
+
+func TestLobby(t *testing.T) {
+	defer sequentialIDs()()
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	keys := map[string]string{}
+	for _, name := range []string{"ann", "bob", "carl"} {
+		keys[name] = register(t, ts.URL, "name="+name).Key
+	}
+	lobby := ts.URL + "/api/v2/game/lobby/"
+	var s seated
+	getJSON(t, lobby+"create?name=friendly&size=9&komi=5.5", keys["ann"], &s)
+	if expected := (seated{"1", game.Black, 1}); s != expected {
+		t.Errorf("expected created %+v, got %+v", expected, s)
+	}
+	getJSON(t, lobby+"challenge?opponent=bob&size=13&rated=true", keys["ann"], &s)
+	if expected := (seated{"2", game.Black, 2}); s != expected {
+		t.Errorf("expected challenge %+v, got %+v", expected, s)
+	}
+
+	// Only bob sees the challenge
+	for player, n := range map[string]int{"": 1, "carl": 1, "bob": 2} {
+		var open []OpenGame
+		getJSON(t, lobby, keys[player], &open)
+		if len(open) != n {
+			t.Fatalf("expected %q to see %d games, got %+v", player, n, open)
+		}
+		if o := open[0]; o.Game != 1 || o.Name != "friendly" || o.Creator != "ann" || o.Size != 9 || o.Rules.Komi != 5.5 {
+			t.Errorf("unexpected open game %+v", o)
+		}
+		if n == 2 && (open[1].Opponent != "bob" || !open[1].Rated) {
+			t.Errorf("unexpected challenge %+v", open[1])
+		}
+	}
+
+	// The challenge is neither paired by start nor joined by others
+	getJSON(t, ts.URL+"/api/v2/game/start/?size=13&rated=true", keys["carl"], &s)
+	if s.Color != game.Black {
+		t.Errorf("expected start not to join a challenge, got %+v", s)
+	}
+	errors := []struct {
+		query  string
+		key    string
+		status int
+		code   ErrorCode
+	}{
+		{"join/2", keys["carl"], http.StatusForbidden, CodeChallenge},
+		{"join/2", "", http.StatusForbidden, CodeChallenge},
+		{"join/1", keys["ann"], http.StatusBadRequest, CodeBadRequest},
+		{"join/9", "", http.StatusNotFound, CodeUnknownGame},
+		{"join/1", "guess", http.StatusUnauthorized, CodeUnauthorized},
+		{"challenge?opponent=bob", "", http.StatusUnauthorized, CodeUnauthorized},
+		{"challenge?opponent=dave", keys["ann"], http.StatusNotFound, CodeUnknownPlayer},
+		{"challenge?opponent=ann", keys["ann"], http.StatusBadRequest, CodeBadRequest},
+	}
+	for _, test := range errors {
+		var e Error
+		if status := getJSON(t, lobby+test.query, test.key, &e); status != test.status || e.Code != test.code {
+			t.Errorf("%s: expected %d %s, got %d %+v", test.query, test.status, test.code, status, e)
+		}
+	}
+
+	for _, join := range []struct {
+		query    string
+		key      string
+		expected seated
+	}{
+		{"join/2", keys["bob"], seated{"4", game.White, 2}},
+		{"join/1", "", seated{"5", game.White, 1}},
+	} {
+		getJSON(t, lobby+join.query, join.key, &s)
+		if s != join.expected {
+			t.Errorf("%s: expected %+v, got %+v", join.query, join.expected, s)
+		}
+	}
+	var open []OpenGame
+	getJSON(t, lobby, "", &open)
+	if len(open) != 1 || open[0].Game != 3 {
+		t.Errorf("expected only the started game left open, got %+v", open)
+	}
+	var state game.PublicState
+	getJSON(t, ts.URL+"/api/v2/game/play/5/state", "", &state)
+	if state.Rules.Komi != 5.5 || state.CurrentPlayer != game.Black {
+		t.Errorf("expected joined game to keep its settings, got %+v", state)
+	}
+}
+
+func TestSettingsQuery(t *testing.T) {
+	tests := []Settings{
+		DefaultSettings,
+		{Size: 9, Rules: game.Rules{Komi: 5.5, Scoring: game.AreaScoring, Ko: game.SimpleKo}, Rated: true},
+		{Size: 19, Rules: game.Rules{
+			Komi:     0.5,
+			Handicap: game.Handicap{Stones: 4, Placement: game.FreePlacement},
+			Time:     game.TimeControl{System: game.Canadian, Main: 10 * time.Minute, Period: 5 * time.Minute, Stones: 25},
+		}},
+	}
+	for _, test := range tests {
+		s, err := parseSettings(test.Query())
+		if err != nil || s != test {
+			t.Errorf("%v: expected %+v, got %+v '%v'", test.Query(), test, s, err)
+		}
+	}
+}
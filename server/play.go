@@ -7,55 +7,83 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
 )
 
-func playHandler(w http.ResponseWriter, r *http.Request) {
+func (a *api) playHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := parseGameID(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, err)
 		return
 	}
 	action, err := parseAction(r)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, err)
 		return
 	}
-	g, ok := gameMap[id]
-	if !ok {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("id %d is not registered", id))
+	g, err := a.store.Lookup(id)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	if g.gameOver {
-		writeJSON(w, "Game Over")
-		return
+	// A registered player's seat also needs their key
+	if c, ok := g.color(id); ok {
+		if err := a.authorize(g, c, r); err != nil {
+			writeError(w, r, err)
+			return
+		}
 	}
 	switch action {
 	case "state":
-		g.stateHandler(w, r)
+		a.stateHandler(g, w, r)
+		return
+	case "history":
+		g.historyHandler(w, r)
+		return
+	case "result":
+		g.resultHandler(w, r)
+		return
+	case "ws":
+		a.channelHandler(g, w, r, id)
+		return
+	case "events":
+		a.streamHandler(g, w, r)
+		return
+	}
+	// Finished games only answer questions about how they went
+	if g.over() {
+		g.writeGameOver(w, r)
+		return
+	}
+	switch action {
 	case "move":
-		g.moveHandler(w, r, id)
+		a.moveHandler(g, w, r, id)
+	case "undo":
+		a.undoHandler(g, w, r, id)
+	case "resign":
+		a.resignHandler(g, w, r, id)
 	case "wait":
 		g.waitHandler(w, r, id)
 	default:
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is not a valid play action", action))
+		writeError(w, r, newError(CodeUnknownAction, "%s is not a valid play action", action))
 	}
 }
 
 func parseGameID(r *http.Request) (GameID, error) {
 	path := strings.Trim(r.URL.Path, "/")
 	parts := strings.SplitN(path, "/", 2)
-	id, err := strconv.ParseUint(parts[0], 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("id %s error: %s", parts[0], err.Error())
+	if parts[0] == "" {
+		return "", newError(CodeUnknownID, "Missing id")
 	}
-	return GameID(id), nil
+	return GameID(parts[0]), nil
 }
 
 func parseAction(r *http.Request) (string, error) {
 	path := strings.Trim(r.URL.Path, "/")
 	parts := strings.Split(path, "/")
 	if len(parts) < 2 {
-		return "", fmt.Errorf("Missing play action")
+		return "", newError(CodeUnknownAction, "Missing play action")
 	}
 	return parts[1], nil
 }
@@ -66,14 +94,47 @@ func writeJSON(w http.ResponseWriter, i interface{}) {
 	w.Header().Add("Access-Control-Allow-Headers", "X-Requested-With,Content-Type,Accept")
 	b, err := json.Marshal(i)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Write JSON marshal error %v: %s", i, err.Error()))
+		message := fmt.Sprintf("Write JSON marshal error %v: %s", i, err.Error())
+		log.Println(message)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(strconv.Quote(message)))
 		return
 	}
 	w.Write(b)
 }
 
-func writeError(w http.ResponseWriter, status int, message string) {
-	w.WriteHeader(status)
-	writeJSON(w, message)
-	log.Println(message)
+// gameOver is the error /api/v1 sends when a player tries to play a finished game
+type gameOver struct {
+	Error  string      `json:"error"`
+	Result game.Result `json:"result"`
+}
+
+func (g *Game) writeGameOver(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	result, _ := g.state.Result()
+	g.mu.Unlock()
+	if version(r) == 1 {
+		w.WriteHeader(http.StatusConflict)
+		writeJSON(w, gameOver{game.ErrGameOver.Error(), result})
+		return
+	}
+	writeError(w, r, Error{CodeGameOver, game.ErrGameOver.Error(), result})
+}
+
+// writeError sends err as an Error with its status under /api/v2,
+// and as a message with status 400, or 500 for internal errors, under /api/v1
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := toError(err)
+	log.Println(e.Message)
+	if version(r) == 1 {
+		status := http.StatusBadRequest
+		if e.Code == CodeInternal {
+			status = http.StatusInternalServerError
+		}
+		w.WriteHeader(status)
+		writeJSON(w, e.Message)
+		return
+	}
+	w.WriteHeader(e.Status())
+	writeJSON(w, e)
 }
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// KeyHeader is the header a registered player sends their API key in
+const KeyHeader = "X-API-Key"
+
+// Kind tells human players from bots
+type Kind int
+
+const (
+	Human = Kind(iota)
+	Bot
+)
+
+func (k Kind) String() string {
+	if k == Bot {
+		return "bot"
+	}
+	return "human"
+}
+
+func (k Kind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+func (k *Kind) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	var err error
+	*k, err = parseKind(s)
+	return err
+}
+
+func parseKind(s string) (Kind, error) {
+	switch strings.ToLower(s) {
+	case "", "human":
+		return Human, nil
+	case "bot":
+		return Bot, nil
+	}
+	return Human, fmt.Errorf("%s is not a kind of player", s)
+}
+
+// Player is a registered human or bot. Bots name their owner and what they are written in.
+type Player struct {
+	ID       uint64 `json:"id"`
+	Name     string `json:"name"`
+	Kind     Kind   `json:"kind"`
+	Owner    string `json:"owner,omitempty"`
+	Language string `json:"language,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// registration is sent once to a new player, as only a hash of their key is kept
+type registration struct {
+	Player Player `json:"player"`
+	Key    string `json:"key"`
+}
+
+// maxName is the longest player name
+const maxName = 64
+
+// randomHex returns n random bytes as hex, for values that must not be guessed
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("unable to read random bytes: %s", err.Error()))
+	}
+	return hex.EncodeToString(b)
+}
+
+// newGameID returns the unguessable GameID of a new seat
+var newGameID = func() GameID {
+	return GameID(randomHex(16))
+}
+
+// hashKey is what a Store keeps of an API key
+func hashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// authenticate returns the player whose key is sent with r, or nil if no key is sent
+func (a *api) authenticate(r *http.Request) (*Player, error) {
+	key := r.Header.Get(KeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+	p, err := a.store.Authenticate(hashKey(key))
+	if err != nil {
+		return nil, newError(CodeUnauthorized, "API key is not registered")
+	}
+	return &p, nil
+}
+
+// authorize checks that r carries the key of the player seated as c in g, if the seat is theirs
+func (a *api) authorize(g *Game, c game.Color, r *http.Request) error {
+	account := g.account(c)
+	if account == 0 {
+		return nil
+	}
+	p, err := a.authenticate(r)
+	switch {
+	case err != nil:
+		return err
+	case p == nil:
+		return newError(CodeUnauthorized, "%s is played by a registered player, send their API key in %s", c, KeyHeader)
+	case p.ID != account:
+		return newError(CodeForbidden, "%s is played by another player", c)
+	}
+	return nil
+}
+
+// playersHandler registers players at players/register?name=<name>&kind=bot&owner=&language=&version=,
+// describes them at players/<id> and lists their rating changes at players/<id>/ratings
+func (a *api) playersHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] == "register" {
+		a.registerHandler(w, r)
+		return
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		writeError(w, r, newError(CodeUnknownPlayer, "%s is not a player", parts[0]))
+		return
+	}
+	p, err := a.store.Player(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	switch {
+	case len(parts) == 1:
+		writeJSON(w, p)
+		return
+	case parts[1] == "ratings":
+		a.ratingsHandler(w, r, p.ID)
+		return
+	}
+	writeError(w, r, newError(CodeUnknownAction, "%s is not a valid player action", parts[1]))
+}
+
+func (a *api) registerHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p := Player{
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		Owner:    r.FormValue("owner"),
+		Language: r.FormValue("language"),
+		Version:  r.FormValue("version"),
+	}
+	if p.Name == "" || len(p.Name) > maxName {
+		writeError(w, r, newError(CodeBadRequest, "Players need a name of 1-%d characters", maxName))
+		return
+	}
+	var err error
+	if p.Kind, err = parseKind(r.FormValue("kind")); err != nil {
+		writeError(w, r, Error{Code: CodeBadRequest, Message: err.Error()})
+		return
+	}
+	key := randomHex(32)
+	if p, err = a.store.Register(p, hashKey(key)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, registration{p, key})
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// register registers the player described by query
+func register(t *testing.T, url, query string) registration {
+	var r registration
+	if status := getJSON(t, url+"/api/v2/players/register?"+query, "", &r); status != http.StatusOK {
+		t.Fatalf("unable to register %s: %d", query, status)
+	}
+	return r
+}
+
+func TestRegister(t *testing.T) {
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	ann := register(t, ts.URL, "name=ann")
+	bot := register(t, ts.URL, "name=gnugo&kind=bot&owner=ann&language=C&version=3.8")
+	expected := Player{ID: 2, Name: "gnugo", Kind: Bot, Owner: "ann", Language: "C", Version: "3.8"}
+	if bot.Player != expected {
+		t.Errorf("expected %+v, got %+v", expected, bot.Player)
+	}
+	if len(bot.Key) != 64 || bot.Key == ann.Key {
+		t.Errorf("expected distinct random keys, got %q and %q", ann.Key, bot.Key)
+	}
+	var p Player
+	if getJSON(t, ts.URL+"/api/v2/players/2", "", &p); p != expected {
+		t.Errorf("expected to look up %+v, got %+v", expected, p)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		code   ErrorCode
+	}{
+		{"register?name=ann", http.StatusConflict, CodeNameTaken},
+		{"register?name=", http.StatusBadRequest, CodeBadRequest},
+		{"register?name=dave&kind=cat", http.StatusBadRequest, CodeBadRequest},
+		{"3", http.StatusNotFound, CodeUnknownPlayer},
+		{"ann", http.StatusNotFound, CodeUnknownPlayer},
+	}
+	for _, test := range tests {
+		var e Error
+		if status := getJSON(t, ts.URL+"/api/v2/players/"+test.path, "", &e); status != test.status || e.Code != test.code {
+			t.Errorf("%s: expected %d %s, got %d %+v", test.path, test.status, test.code, status, e)
+		}
+	}
+}
+
+func TestSeatKey(t *testing.T) {
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	ann := register(t, ts.URL, "name=ann")
+	bob := register(t, ts.URL, "name=bob")
+	var black, white seated
+	getJSON(t, ts.URL+"/api/v2/game/start/", ann.Key, &black)
+	getJSON(t, ts.URL+"/api/v2/game/start/", "", &white)
+	if len(black.ID) != 32 || black.ID == white.ID {
+		t.Fatalf("expected distinct random GameIDs, got %q and %q", black.ID, white.ID)
+	}
+
+	tests := []struct {
+		id     GameID
+		key    string
+		move   string
+		status int
+		code   ErrorCode
+	}{
+		{black.ID, "", "[3,3]", http.StatusUnauthorized, CodeUnauthorized},
+		{black.ID, bob.Key, "[3,3]", http.StatusForbidden, CodeForbidden},
+		{black.ID, "guess", "[3,3]", http.StatusUnauthorized, CodeUnauthorized},
+		{"1", ann.Key, "[3,3]", http.StatusNotFound, CodeUnknownID},
+		{black.ID, ann.Key, "[3,3]", http.StatusOK, ""},
+		{white.ID, "", "[4,4]", http.StatusOK, ""},
+	}
+	for i, test := range tests {
+		r, _ := http.NewRequest("POST", ts.URL+"/api/v2/game/play/"+string(test.id)+"/move", bytes.NewBufferString(test.move))
+		if test.key != "" {
+			r.Header.Set(KeyHeader, test.key)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var e Error
+		if resp.StatusCode != http.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&e)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status || e.Code != test.code {
+			t.Errorf("%d: expected %d %s, got %d %+v", i, test.status, test.code, resp.StatusCode, e)
+		}
+	}
+	var state game.PublicState
+	getJSON(t, ts.URL+"/api/v2/game/play/"+string(white.ID)+"/state", "", &state)
+	if state.Board[3][3] != game.Black || state.Board[4][4] != game.White {
+		t.Errorf("expected only the moves with credentials to be played, got %v", state.Board)
+	}
+}
@@ -0,0 +1,110 @@
+// Package rating implements the Glicko-2 rating system, as described in
+// Mark Glickman's "Example of the Glicko-2 system" (http://www.glicko.net/glicko/glicko2.pdf).
+package rating
+
+import "math"
+
+// Rating is a player's strength on the Glicko scale. Deviation shrinks as the player's
+// strength becomes more certain, and Volatility is how erratic their results are.
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+}
+
+// Initial is the rating of a player who has not played
+var Initial = Rating{1500, 350, 0.06}
+
+// Tau constrains how quickly volatility changes
+const Tau = 0.5
+
+// scale converts between the Glicko and Glicko-2 scales
+const scale = 173.7178
+
+// epsilon is the tolerance the volatility is found to
+const epsilon = 0.000001
+
+// Result is a game against Opponent, with a Score of 1 for a win, 0.5 for a draw and 0 for a loss
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+func (r Rating) mu() float64 {
+	return (r.Rating - 1500) / scale
+}
+
+func (r Rating) phi() float64 {
+	return r.Deviation / scale
+}
+
+// weight reduces the impact of games against opponents with uncertain ratings
+func weight(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muj, phij float64) float64 {
+	return 1 / (1 + math.Exp(-weight(phij)*(mu-muj)))
+}
+
+// Expected is the score r is expected to make against opponent
+func (r Rating) Expected(opponent Rating) float64 {
+	return expected(r.mu(), opponent.mu(), opponent.phi())
+}
+
+// Update returns the rating after the results of a rating period, with tau constraining
+// the change in volatility. A player without results only becomes less certain.
+func (r Rating) Update(results []Result, tau float64) Rating {
+	mu, phi, sigma := r.mu(), r.phi(), r.Volatility
+	if len(results) == 0 {
+		return Rating{r.Rating, math.Sqrt(phi*phi+sigma*sigma) * scale, sigma}
+	}
+	// v is the estimated variance of the rating from the results alone,
+	// and improvement is how much better the results were than expected
+	var v, improvement float64
+	for _, result := range results {
+		g := weight(result.Opponent.phi())
+		e := expected(mu, result.Opponent.mu(), result.Opponent.phi())
+		v += g * g * e * (1 - e)
+		improvement += g * (result.Score - e)
+	}
+	v = 1 / v
+	delta := v * improvement
+	sigma = volatility(delta, phi, v, sigma, tau)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * improvement
+	return Rating{mu*scale + 1500, phi * scale, sigma}
+}
+
+// volatility finds the new volatility with the Illinois algorithm
+func volatility(delta, phi, v, sigma, tau float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+	A, B := a, 0.0
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB < 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,83 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// The example worked through in Glickman's "Example of the Glicko-2 system"
+var (
+	player    = Rating{1500, 200, 0.06}
+	opponents = []Rating{{1400, 30, 0.06}, {1550, 100, 0.06}, {1700, 300, 0.06}}
+	scores    = []float64{1, 0, 0}
+)
+
+func TestGlickmanIntermediate(t *testing.T) {
+	if !near(player.mu(), 0, 1e-9) || !near(player.phi(), 1.1513, 1e-4) {
+		t.Errorf("expected mu 0 and phi 1.1513, got %.4f %.4f", player.mu(), player.phi())
+	}
+	tests := []struct {
+		mu, phi, g, e float64
+	}{
+		{-0.5756, 0.1727, 0.9955, 0.639},
+		{0.2878, 0.5756, 0.9531, 0.432},
+		{1.1513, 1.7269, 0.7242, 0.303},
+	}
+	for i, test := range tests {
+		o := opponents[i]
+		if !near(o.mu(), test.mu, 1e-4) || !near(o.phi(), test.phi, 1e-4) {
+			t.Errorf("opponent %d: expected mu %.4f phi %.4f, got %.4f %.4f", i, test.mu, test.phi, o.mu(), o.phi())
+		}
+		if g := weight(o.phi()); !near(g, test.g, 1e-4) {
+			t.Errorf("opponent %d: expected g %.4f, got %.4f", i, test.g, g)
+		}
+		if e := player.Expected(o); !near(e, test.e, 1e-3) {
+			t.Errorf("opponent %d: expected E %.3f, got %.3f", i, test.e, e)
+		}
+	}
+	// The paper's v is 1.7785 and its delta -0.4834
+	if sigma := volatility(-0.4834, player.phi(), 1.7785, player.Volatility, 0.5); !near(sigma, 0.05999, 1e-5) {
+		t.Errorf("expected volatility 0.05999, got %.5f", sigma)
+	}
+}
+
+func TestGlickmanExample(t *testing.T) {
+	var results []Result
+	for i, o := range opponents {
+		results = append(results, Result{o, scores[i]})
+	}
+	r := player.Update(results, 0.5)
+	if !near(r.Rating, 1464.06, 0.01) || !near(r.Deviation, 151.52, 0.01) || !near(r.Volatility, 0.05999, 1e-5) {
+		t.Errorf("expected 1464.06 151.52 0.05999, got %.2f %.2f %.5f", r.Rating, r.Deviation, r.Volatility)
+	}
+}
+
+func TestNoResults(t *testing.T) {
+	// Only the deviation grows, to sqrt(phi^2 + sigma^2) on the Glicko-2 scale
+	r := player.Update(nil, Tau)
+	if r.Rating != player.Rating || r.Volatility != player.Volatility || !near(r.Deviation, 200.27, 0.01) {
+		t.Errorf("expected 1500 200.27 0.06, got %.2f %.2f %.5f", r.Rating, r.Deviation, r.Volatility)
+	}
+}
+
+func TestHeadToHead(t *testing.T) {
+	winner := Initial.Update([]Result{{Initial, 1}}, Tau)
+	loser := Initial.Update([]Result{{Initial, 0}}, Tau)
+	drawn := Initial.Update([]Result{{Initial, 0.5}}, Tau)
+	if winner.Rating <= Initial.Rating || !near(winner.Rating-Initial.Rating, Initial.Rating-loser.Rating, 1e-9) {
+		t.Errorf("expected symmetric change, got winner %+v loser %+v", winner, loser)
+	}
+	if winner.Deviation >= Initial.Deviation || !near(winner.Deviation, loser.Deviation, 1e-9) {
+		t.Errorf("expected both deviations to shrink equally, got winner %+v loser %+v", winner, loser)
+	}
+	if !near(drawn.Rating, Initial.Rating, 1e-9) {
+		t.Errorf("expected a draw between equals to keep the rating, got %+v", drawn)
+	}
+	if e := winner.Expected(loser); e <= 0.5 {
+		t.Errorf("expected the winner to be favoured, got %.3f", e)
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server/rating"
+)
+
+// RatingChange is a player's rating after a rated game. Players have a separate
+// rating on each size of board, and each game is its own rating period.
+type RatingChange struct {
+	Player   uint64 `json:"player"`
+	Size     int    `json:"size"`
+	Game     uint64 `json:"game"`
+	Opponent uint64 `json:"opponent"`
+	// Score is 1 for a win, 0.5 for a draw and 0 for a loss
+	Score float64 `json:"score"`
+	rating.Rating
+	// Games is the number of rated games the player has played on the board size
+	Games int       `json:"games"`
+	Time  time.Time `json:"time"`
+}
+
+// Standing is a player's place on the leaderboard
+type Standing struct {
+	Rank   int    `json:"rank"`
+	Player Player `json:"player"`
+	rating.Rating
+	Games int `json:"games"`
+}
+
+// rate updates the ratings of the registered players of g once it has ended, if it is rated
+func (a *api) rate(g *Game) {
+	g.mu.Lock()
+	result, over := g.state.Result()
+	black, white := g.accounts[game.Black], g.accounts[game.White]
+	if !over || !g.rated || g.ratedDone || black == 0 || white == 0 {
+		g.mu.Unlock()
+		return
+	}
+	g.ratedDone = true
+	size := g.state.Size()
+	g.mu.Unlock()
+
+	score := 0.5
+	switch result.Winner {
+	case game.Black:
+		score = 1
+	case game.White:
+		score = 0
+	}
+	// Both players are rated from their ratings before the game
+	a.ratings.Lock()
+	defer a.ratings.Unlock()
+	b, err := a.latest(black, size)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	w, err := a.latest(white, size)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	now := a.clock.Now()
+	changes := []RatingChange{
+		{black, size, g.id, white, score, b.Update([]rating.Result{{w.Rating, score}}, rating.Tau), b.Games + 1, now},
+		{white, size, g.id, black, 1 - score, w.Update([]rating.Result{{b.Rating, 1 - score}}, rating.Tau), w.Games + 1, now},
+	}
+	if err := a.store.Rate(changes...); err != nil {
+		log.Println(err)
+	}
+}
+
+// latest is the player's last rating change on boards of size, or the initial rating
+func (a *api) latest(player uint64, size int) (RatingChange, error) {
+	changes, err := a.store.Ratings(player)
+	if err != nil {
+		return RatingChange{}, err
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Size == size {
+			return changes[i], nil
+		}
+	}
+	return RatingChange{Player: player, Size: size, Rating: rating.Initial}, nil
+}
+
+// parseSize reads the board size a request filters by, or def if there is none
+func parseSize(r *http.Request, def int) (int, error) {
+	s := r.FormValue("size")
+	if s == "" {
+		return def, nil
+	}
+	size, err := strconv.Atoi(s)
+	if err != nil || size < minSize || size > maxSize {
+		return 0, newError(CodeBadRequest, "%s is not a valid size, boards are %d-%d", s, minSize, maxSize)
+	}
+	return size, nil
+}
+
+// leaderboardHandler ranks the players rated on boards of size=<size>, 19 by default,
+// optionally only those of kind=<human|bot>
+func (a *api) leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	size, err := parseSize(r, DefaultSettings.Size)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var kind *Kind
+	if k := r.FormValue("kind"); k != "" {
+		parsed, err := parseKind(k)
+		if err != nil {
+			writeError(w, r, Error{Code: CodeBadRequest, Message: err.Error()})
+			return
+		}
+		kind = &parsed
+	}
+	current, err := a.store.Current(size)
+	if err != nil {
+		writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+		return
+	}
+	board := []Standing{}
+	for _, c := range current {
+		p, err := a.store.Player(c.Player)
+		if err != nil {
+			writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+			return
+		}
+		if kind == nil || p.Kind == *kind {
+			board = append(board, Standing{Player: p, Rating: c.Rating, Games: c.Games})
+		}
+	}
+	sort.SliceStable(board, func(i, j int) bool {
+		return board[i].Rating.Rating > board[j].Rating.Rating
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+	}
+	writeJSON(w, board)
+}
+
+// ratingsHandler writes the rating history of player, only on boards of size=<size> if given
+func (a *api) ratingsHandler(w http.ResponseWriter, r *http.Request, player uint64) {
+	size, err := parseSize(r, 0)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	changes, err := a.store.Ratings(player)
+	if err != nil {
+		writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+		return
+	}
+	history := []RatingChange{}
+	for _, c := range changes {
+		if size == 0 || c.Size == size {
+			history = append(history, c)
+		}
+	}
+	writeJSON(w, history)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server/rating"
+)
+
+// resignedGame starts a game between black and white, which the loser resigns
+func resignedGame(t *testing.T, url, query, black, white string, loser game.Color) {
+	var b, w seated
+	getJSON(t, url+"/api/v2/game/start/?"+query, black, &b)
+	getJSON(t, url+"/api/v2/game/start/?"+query, white, &w)
+	id, key := b.ID, black
+	if loser == game.White {
+		id, key = w.ID, white
+	}
+	var result game.Result
+	if status := getJSON(t, url+"/api/v2/game/play/"+string(id)+"/resign", key, &result); status != http.StatusOK {
+		t.Fatalf("unable to resign %s: %d", id, status)
+	}
+}
+
+func TestRatings(t *testing.T) {
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	ann := register(t, ts.URL, "name=ann").Key
+	bob := register(t, ts.URL, "name=bob&kind=bot").Key
+	carl := register(t, ts.URL, "name=carl&kind=bot").Key
+
+	resignedGame(t, ts.URL, "size=9&rated=true", ann, bob, game.White)
+	resignedGame(t, ts.URL, "size=9&rated=true", carl, bob, game.Black)
+	resignedGame(t, ts.URL, "size=13&rated=true", carl, ann, game.Black)
+	// Unrated games change nothing
+	resignedGame(t, ts.URL, "size=9", bob, ann, game.White)
+
+	// bob loses then wins, rated from his rating after the first game
+	first := rating.Initial.Update([]rating.Result{{rating.Initial, 0}}, rating.Tau)
+	winner := rating.Initial.Update([]rating.Result{{rating.Initial, 1}}, rating.Tau)
+	second := first.Update([]rating.Result{{rating.Initial, 1}}, rating.Tau)
+	var history []RatingChange
+	getJSON(t, ts.URL+"/api/v2/players/2/ratings", "", &history)
+	if len(history) != 2 {
+		t.Fatalf("expected 2 rating changes for bob, got %+v", history)
+	}
+	if h := history[0]; h.Game != 1 || h.Opponent != 1 || h.Score != 0 || h.Games != 1 || !sameRating(h.Rating, first) {
+		t.Errorf("expected %+v after losing, got %+v", first, h)
+	}
+	if h := history[1]; h.Game != 2 || h.Opponent != 3 || h.Score != 1 || h.Games != 2 || !sameRating(h.Rating, second) {
+		t.Errorf("expected %+v after winning, got %+v", second, h)
+	}
+	getJSON(t, ts.URL+"/api/v2/players/1/ratings?size=13", "", &history)
+	if len(history) != 1 || history[0].Size != 13 || history[0].Score != 1 {
+		t.Errorf("expected ann's 13x13 win, got %+v", history)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"?size=9", []string{"ann", "bob", "carl"}},
+		{"?size=9&kind=bot", []string{"bob", "carl"}},
+		{"?size=13", []string{"ann", "carl"}},
+		{"", []string{}},
+	}
+	for _, test := range tests {
+		var board []Standing
+		getJSON(t, ts.URL+"/api/v2/leaderboard"+test.query, "", &board)
+		if len(board) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %+v", test.query, test.expected, board)
+			continue
+		}
+		for i, s := range board {
+			if s.Rank != i+1 || s.Player.Name != test.expected[i] {
+				t.Errorf("%s: expected %d %s, got %+v", test.query, i+1, test.expected[i], s)
+			}
+		}
+	}
+	var board []Standing
+	getJSON(t, ts.URL+"/api/v2/leaderboard?size=9", "", &board)
+	if len(board) == 3 && (!sameRating(board[0].Rating, winner) || board[1].Games != 2) {
+		t.Errorf("unexpected leaderboard %+v", board)
+	}
+	var e Error
+	if status := getJSON(t, ts.URL+"/api/v2/leaderboard?size=100", "", &e); status != http.StatusBadRequest || e.Code != CodeBadRequest {
+		t.Errorf("expected bad size to be refused, got %d %+v", status, e)
+	}
+}
+
+func sameRating(a, b rating.Rating) bool {
+	return math.Abs(a.Rating-b.Rating) < 1e-9 && math.Abs(a.Deviation-b.Deviation) < 1e-9 && math.Abs(a.Volatility-b.Volatility) < 1e-9
+}
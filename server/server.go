@@ -4,163 +4,481 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gophergala2016/gobotgo/game"
 )
 
-type GameID uint64
+// GameID is a player's seat in a game, and the secret they play it with
+type GameID string
 
-type gameIDChan chan GameID
+// Pieces is the number of stones each player starts with
+const Pieces = 180
 
 type Game struct {
-	state    *game.State
-	players  map[GameID]game.Color
+	id      uint64
+	mu      sync.Mutex
+	state   *game.State
+	players map[GameID]game.Color
+	// accounts are the registered players seated, by color
+	accounts map[game.Color]uint64
 	turn     chan game.Color
 	gameOver bool
+	// undo is the player asking to take back the last turn
+	undo game.Color
+	// clocks hold each player's time when the rules have a time control,
+	// and timer ends the game if the player started at started runs out
+	clocks  *Clocks
+	started time.Time
+	timer   Timer
+	// timing counts the turns timed, so a stale timer does nothing
+	timing uint64
+	rated  bool
+	// listing is how the game is shown while it waits in the lobby
+	listing Listing
+	// ratedDone is set once the players' ratings have been updated
+	ratedDone bool
+	// changing is held while the game's changes are published, so they are published in order.
+	// published is the number of turns published, and ended is set once the end has been.
+	changing  sync.Mutex
+	published int
+	ended     bool
+	// event is the tournament the game is played in, as the pairing at that index
+	event   *event
+	pairing int
+	// log is every event published, numbered from 1, and chats counts the chat messages in it
+	log   []Event
+	chats int
+	// watchers receive the game's events as they are published
+	watchers map[chan Event]bool
 }
 
-var nextGame = make(chan *Game, 1)
-var masterID = make(gameIDChan, 1)
-var gameMap = map[GameID]*Game{}
-
-const notSet GameID = 0
+// publicGame is the state sent to players, with the game number spectators watch it by
+type publicGame struct {
+	Game uint64 `json:"game"`
+	game.PublicState
+	UndoRequest game.Color `json:"undorequest,omitempty"`
+	Clocks      *Clocks    `json:"clocks,omitempty"`
+}
 
 var passErr = fmt.Errorf("Pass")
 
-func init() {
-	nextGame <- &Game{}
-	masterID <- 1
+func newGame(id uint64, s *game.State) *Game {
+	g := &Game{
+		id:       id,
+		state:    s,
+		players:  map[GameID]game.Color{},
+		accounts: map[game.Color]uint64{},
+		turn:     make(chan game.Color, 1),
+		watchers: map[chan Event]bool{},
+	}
+	g.turn <- s.Public().CurrentPlayer
+	return g
 }
 
-func MuxerAPIv1() http.Handler {
-	root := "/api/v1"
-	mux := http.NewServeMux()
-	mux.HandleFunc(root+"/game/start/", startHandler)
-	play := root + "/game/play/"
-	mux.Handle(play, http.StripPrefix(play, http.HandlerFunc(playHandler)))
-	return mux
+// seat adds player id to the game, played by account if they are registered
+func (g *Game) seat(id GameID, c game.Color, account uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.players[id] = c
+	if account != 0 {
+		g.accounts[c] = account
+	}
+}
+
+// account is the registered player seated as c, or 0
+func (g *Game) account(c game.Color) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.accounts[c]
+}
+
+func (g *Game) color(id GameID) (game.Color, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c, ok := g.players[id]
+	return c, ok
+}
+
+func (g *Game) over() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.gameOver
+}
+
+func (g *Game) settings() Settings {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Settings{g.state.Size(), g.state.Rules(), g.rated}
 }
 
-func startHandler(w http.ResponseWriter, r *http.Request) {
-	r.ParseForm()
-	size := parseSize(r)
-	g := <-nextGame
-	if g.state == nil {
-		g.state = game.New(size, 180)
-		g.players = map[GameID]game.Color{}
-		g.turn = make(chan game.Color, 1)
-		g.turn <- game.Black
+func (g *Game) seated() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.players)
+}
+
+// replay applies a stored event, used when restoring games from a Store
+func (g *Game) replay(e GameEvent) error {
+	<-g.turn
+	g.mu.Lock()
+	err := e.apply(g.state)
+	g.gameOver = g.state.Over()
+	// Restored events have already been published
+	g.published, g.ended = len(g.state.History()), g.gameOver
+	t := g.state.Public().CurrentPlayer
+	g.mu.Unlock()
+	g.turn <- t
+	return err
+}
+
+// api serves the games in a Store
+type api struct {
+	store Store
+	clock Clock
+	mu    sync.Mutex
+	// lobby holds the games waiting for a second player, oldest first
+	lobby []*OpenGame
+	// ratings is held while players' ratings are updated
+	ratings sync.Mutex
+	// events are the tournaments, in the order they were created
+	events []*event
+	// bus carries the changes to every game to the subscribers below
+	bus   Bus
+	stats *stats
+}
+
+func newAPI(store Store) *api {
+	return newTimedAPI(store, realClock{})
+}
+
+// newTimedAPI serves the games in store, timing players with clock
+func newTimedAPI(store Store, clock Clock) *api {
+	a := &api{store: store, clock: clock, stats: newStats()}
+	// Notifications go out before anything slower happens
+	a.bus.Subscribe(a.notify)
+	a.bus.Subscribe(a.persist)
+	a.bus.Subscribe(a.ended)
+	a.bus.Subscribe(a.stats.count)
+	// A game left waiting for an opponent goes back in the lobby
+	games, err := store.List()
+	if err != nil {
+		log.Println(err)
 	}
-	id := masterID.next()
-	var c game.Color
-	switch {
-	case len(g.players) == 0:
-		c = game.Black
-		nextGame <- g
-	case len(g.players) == 1:
-		c = game.White
-		nextGame <- &Game{}
-	}
-	gameMap[id] = g
-	g.players[id] = c
-	s := struct {
-		ID    GameID     `json: "id"`
-		Color game.Color `json:"color"`
-	}{
-		id, c,
+	for _, g := range games {
+		switch g.seated() {
+		case 1:
+			o := &OpenGame{game: g, Game: g.id, Listing: g.listing, Settings: g.settings(), account: g.account(game.Black)}
+			if p, err := store.Player(o.account); err == nil {
+				o.Creator = p.Name
+			}
+			a.lobby = append(a.lobby, o)
+		case 2:
+			// Players get their full time back after a restart
+			g.mu.Lock()
+			a.startClock(g)
+			g.mu.Unlock()
+		}
 	}
-	writeJSON(w, &s)
+	return a
+}
+
+// MuxerAPIv1 serves /api/v1, which sends errors as plain messages
+func MuxerAPIv1(store Store) http.Handler {
+	mux := http.NewServeMux()
+	newAPI(store).routes(mux, 1)
+	return mux
 }
 
-func parseSize(r *http.Request) int {
-	size, _ := strconv.Atoi(r.FormValue("size"))
-	if size == 0 {
-		return 19
+// MuxerAPI serves /api/v1 and /api/v2, which sends errors as an Error with a stable code
+func MuxerAPI(store Store) http.Handler {
+	a := newAPI(store)
+	mux := http.NewServeMux()
+	a.routes(mux, 1)
+	a.routes(mux, 2)
+	return mux
+}
+
+// routes adds version v of the API to mux
+func (a *api) routes(mux *http.ServeMux, v int) {
+	root := fmt.Sprintf("/api/v%d", v)
+	mux.Handle(root+"/game/start/", versioned(v, http.HandlerFunc(a.startHandler)))
+	lobby := root + "/game/lobby/"
+	mux.Handle(lobby, versioned(v, http.StripPrefix(lobby, http.HandlerFunc(a.lobbyHandler))))
+	play := root + "/game/play/"
+	mux.Handle(play, versioned(v, http.StripPrefix(play, http.HandlerFunc(a.playHandler))))
+	if v >= 2 {
+		players := root + "/players/"
+		mux.Handle(players, versioned(v, http.StripPrefix(players, http.HandlerFunc(a.playersHandler))))
+		mux.Handle(root+"/leaderboard", versioned(v, http.HandlerFunc(a.leaderboardHandler)))
+		mux.Handle(root+"/stats", versioned(v, http.HandlerFunc(a.statsHandler)))
+		games := root + "/games/"
+		mux.Handle(games, versioned(v, http.StripPrefix(games, http.HandlerFunc(a.gamesHandler))))
+		tournaments := root + "/tournaments/"
+		mux.Handle(tournaments, versioned(v, http.StripPrefix(tournaments, http.HandlerFunc(a.tournamentsHandler))))
 	}
-	return size
 }
 
-func (c gameIDChan) next() GameID {
-	id := <-c
-	c <- id + 1
-	return id
+func (a *api) stateHandler(g *Game, w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeJSON(w, a.public(g))
 }
 
-func (id GameID) String() string {
-	return strconv.FormatUint(uint64(id), 10)
+// public is the state of g sent to players, and must be called with g.mu held
+func (a *api) public(g *Game) publicGame {
+	return publicGame{g.id, g.state.Public(), g.undo, a.timeLeft(g)}
 }
 
-func (g Game) stateHandler(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, g.state)
+// final is how a finished game ended, with the final position and its score
+type final struct {
+	Winner game.Color  `json:"winner"`
+	Reason game.Reason `json:"reason"`
+	Score  game.Score  `json:"score"`
+	Board  game.Board  `json:"board"`
 }
 
-func (g *Game) moveHandler(w http.ResponseWriter, r *http.Request, id GameID) {
+// resultHandler writes how the game ended, once it is over
+func (g *Game) resultHandler(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	result, ok := g.state.Result()
+	if !ok {
+		writeError(w, r, newError(CodeGameNotOver, "Game is not over"))
+		return
+	}
+	writeJSON(w, final{result.Winner, result.Reason, g.state.Score(), g.state.Public().Board})
+}
+
+// historyHandler writes the setup and every turn played, which game.Replay can step through
+func (g *Game) historyHandler(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeJSON(w, g.state.Record())
+}
+
+func (a *api) moveHandler(g *Game, w http.ResponseWriter, r *http.Request, id GameID) {
 	t := <-g.turn
-	p, ok := g.players[id]
+	p, ok := g.color(id)
 	if !ok {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("No player for id %d", id))
+		writeError(w, r, newError(CodeUnknownID, "No player for id %s", id))
 		g.turn <- t
 		return
 	}
 	m, err := g.parseMove(r, p)
 	if err != nil {
 		if err == passErr {
-			g.pass(w, p)
-			g.changeTurn(t)
+			a.pass(g, w, r, p)
+			g.nextTurn()
 			return
 		}
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, err)
 		g.turn <- t
 		return
 	}
-	if err := g.state.Move(m); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	g.mu.Lock()
+	d, ok := a.thinking(g, p)
+	err = g.state.Move(m)
+	switch err {
+	case nil:
+		g.undo = game.None
+		a.moved(g, p, d)
+	case game.ErrGameOver:
+		// Running out of stones or time ends the game
+		a.stopClock(g)
+		g.gameOver = true
+	}
+	g.mu.Unlock()
+	if !ok || err == game.ErrGameOver {
+		a.changed(g)
+	}
+	if err != nil {
+		if err == game.ErrGameOver {
+			g.writeGameOver(w, r)
+		} else {
+			writeError(w, r, err)
+		}
 		g.turn <- t
 		return
 	}
+	a.changed(g)
 	writeJSON(w, "valid")
-	g.changeTurn(t)
+	g.nextTurn()
 }
 
-func (g *Game) pass(w http.ResponseWriter, c game.Color) {
-	if err := g.state.Pass(c); err != nil {
-		if err == game.ErrGameOver {
-			g.gameOver = true
-			writeJSON(w, err.Error())
+func (a *api) pass(g *Game, w http.ResponseWriter, r *http.Request, c game.Color) {
+	g.mu.Lock()
+	played := len(g.state.History())
+	d, _ := a.thinking(g, c)
+	err := g.state.Pass(c)
+	switch err {
+	case nil:
+		a.moved(g, c, d)
+	case game.ErrGameOver:
+		a.stopClock(g)
+		g.gameOver = true
+	}
+	if len(g.state.History()) > played {
+		g.undo = game.None
+	}
+	g.mu.Unlock()
+	a.changed(g)
+	switch err {
+	case nil:
+		writeJSON(w, "valid")
+	case game.ErrGameOver:
+		g.writeGameOver(w, r)
+	default:
+		writeError(w, r, err)
+	}
+}
+
+// undoHandler takes back the last turn once both players agree.
+// A player asks with undo, and the opponent answers with undo?answer=accept or undo?answer=reject.
+func (a *api) undoHandler(g *Game, w http.ResponseWriter, r *http.Request, id GameID) {
+	p, ok := g.color(id)
+	if !ok {
+		writeError(w, r, newError(CodeUnknownID, "No player for id %s", id))
+		return
+	}
+	t := <-g.turn
+	g.mu.Lock()
+	answer := r.FormValue("answer")
+	switch {
+	case g.state.Over():
+		g.mu.Unlock()
+		g.turn <- t
+		g.writeGameOver(w, r)
+		return
+	case answer == "":
+		if len(g.state.History()) == 0 {
+			g.mu.Unlock()
+			g.turn <- t
+			writeError(w, r, game.ErrNoUndo)
 			return
 		}
-		writeError(w, http.StatusBadRequest, err.Error())
+		g.undo = p
+		g.mu.Unlock()
+		g.turn <- t
+		writeJSON(w, "requested")
+		return
+	case g.undo != p.Opponent():
+		g.mu.Unlock()
+		g.turn <- t
+		writeError(w, r, newError(CodeNoUndoRequest, "No undo requested by opponent"))
+		return
+	case answer == "reject":
+		g.undo = game.None
+		g.mu.Unlock()
+		g.turn <- t
+		writeJSON(w, "rejected")
+		return
+	case answer != "accept":
+		g.mu.Unlock()
+		g.turn <- t
+		writeError(w, r, newError(CodeBadRequest, "%s is not a valid undo answer", answer))
+		return
 	}
-	writeJSON(w, "valid")
+	err := g.state.Undo()
+	if err == nil {
+		g.undo = game.None
+		g.gameOver = g.state.Over()
+		t = g.state.Public().CurrentPlayer
+		a.startClock(g)
+	}
+	g.mu.Unlock()
+	g.turn <- t
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	a.undone(g)
+	writeJSON(w, "undone")
 }
 
+// resignHandler ends the game with a win for the opponent and writes the result.
+// Players can resign at any time, so it does not wait for the turn.
+func (a *api) resignHandler(g *Game, w http.ResponseWriter, r *http.Request, id GameID) {
+	p, ok := g.color(id)
+	if !ok {
+		writeError(w, r, newError(CodeUnknownID, "No player for id %s", id))
+		return
+	}
+	g.mu.Lock()
+	err := g.state.Resign(p)
+	if err == nil {
+		a.stopClock(g)
+		g.gameOver = true
+		g.undo = game.None
+	}
+	result, _ := g.state.Result()
+	g.mu.Unlock()
+	switch err {
+	case nil:
+	case game.ErrGameOver:
+		g.writeGameOver(w, r)
+		return
+	default:
+		writeError(w, r, err)
+		return
+	}
+	a.changed(g)
+	writeJSON(w, result)
+}
+
+// waitHandler returns once it is the player's turn, or the game is over.
+// With since=<n> it returns once more than n turns have been played instead, at once if they have,
+// so a player who has seen n turns misses none. It answers 204 No Content after timeout=<duration>.
 func (g *Game) waitHandler(w http.ResponseWriter, r *http.Request, id GameID) {
-	p, ok := g.players[id]
+	p, ok := g.color(id)
 	if !ok {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("No player for id %d", id))
+		writeError(w, r, newError(CodeUnknownID, "No player for id %s", id))
 		return
 	}
-	var t game.Color
-	for t = <-g.turn; t != p; t = <-g.turn {
-		g.turn <- t
+	since := -1
+	if v := r.FormValue("since"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a valid number of turns", v))
+			return
+		}
+		since = n
+	}
+	// Both players stop waiting once the game is over
+	done := func() bool {
+		switch {
+		case g.gameOver:
+			return true
+		case since >= 0:
+			return len(g.state.History()) > since
+		}
+		return g.state.Public().CurrentPlayer == p
+	}
+	if !g.awaitRequest(w, r, done) {
+		return
+	}
+	if g.over() {
+		g.writeGameOver(w, r)
+		return
 	}
-	g.turn <- t
 	writeJSON(w, "go bot go")
 }
 
-func (g Game) parseMove(r *http.Request, c game.Color) (game.Move, error) {
+func (g *Game) parseMove(r *http.Request, c game.Color) (game.Move, error) {
 	d := json.NewDecoder(r.Body)
 	var move []int
 	if err := d.Decode(&move); err != nil {
-		return game.Move{}, fmt.Errorf("Decode move error: %s", err.Error())
+		return game.Move{}, newError(CodeBadCoordinates, "Decode move error: %s", err.Error())
 	}
 	if len(move) == 0 {
 		return game.Move{}, passErr
 	}
 	if len(move) != 2 {
-		return game.Move{}, fmt.Errorf("Move has %d coordinates", len(move))
+		return game.Move{}, newError(CodeBadCoordinates, "Move has %d coordinates", len(move))
 	}
 	return game.Move{
 		Player:   c,
@@ -168,11 +486,11 @@ func (g Game) parseMove(r *http.Request, c game.Color) (game.Move, error) {
 	}, nil
 }
 
-func (g Game) changeTurn(t game.Color) {
-	switch t {
-	case game.Black:
-		g.turn <- game.White
-	default:
-		g.turn <- game.Black
-	}
+// nextTurn hands the turn to the state's current player,
+// who is not always the opponent while Black places free handicap stones
+func (g *Game) nextTurn() {
+	g.mu.Lock()
+	t := g.state.Public().CurrentPlayer
+	g.mu.Unlock()
+	g.turn <- t
 }
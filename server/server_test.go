@@ -2,10 +2,14 @@ package server
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -29,8 +33,27 @@ func (tw testWriter) Header() http.Header {
 
 var wg sync.WaitGroup
 
+// sequentialIDs gives out GameIDs 1, 2, 3... until the returned function is called
+func sequentialIDs() func() {
+	random := newGameID
+	var next uint64
+	newGameID = func() GameID {
+		return GameID(strconv.FormatUint(atomic.AddUint64(&next, 1), 10))
+	}
+	return func() { newGameID = random }
+}
+
+// gameIsOver reports whether a response is the game over error
+func gameIsOver(content []byte) bool {
+	var over gameOver
+	return json.Unmarshal(content, &over) == nil && over.Error == game.ErrGameOver.Error()
+}
+
 func TestStartHandler(t *testing.T) {
+	defer sequentialIDs()()
 	r, _ := http.NewRequest("GET", "/", nil)
+	store := NewMemoryStore()
+	a := newAPI(store)
 	tests := []struct {
 		input    *http.Request
 		expected map[GameID]*Game
@@ -39,9 +62,9 @@ func TestStartHandler(t *testing.T) {
 		{
 			r,
 			map[GameID]*Game{
-				1: &Game{
-					state:   game.New(19, 180),
-					players: map[GameID]game.Color{1: game.Black},
+				"1": &Game{
+					state:   game.MustNew(19, 180, game.DefaultRules),
+					players: map[GameID]game.Color{"1": game.Black},
 				},
 			},
 			"One person has joined",
@@ -49,61 +72,63 @@ func TestStartHandler(t *testing.T) {
 		{
 			r,
 			map[GameID]*Game{
-				1: &Game{
-					state:   game.New(19, 180),
-					players: map[GameID]game.Color{1: game.Black, 2: game.White},
+				"1": &Game{
+					state:   game.MustNew(19, 180, game.DefaultRules),
+					players: map[GameID]game.Color{"1": game.Black, "2": game.White},
 				},
-				2: &Game{
-					state:   game.New(19, 180),
-					players: map[GameID]game.Color{1: game.Black, 2: game.White},
+				"2": &Game{
+					state:   game.MustNew(19, 180, game.DefaultRules),
+					players: map[GameID]game.Color{"1": game.Black, "2": game.White},
 				},
 			},
 			"Two people have joined",
 		},
 	}
 	for _, test := range tests {
-		startHandler(&testWriter{}, test.input)
-		if !gameEqual(test.expected, gameMap) {
-			t.Errorf("%s not equal:\nexpected:%v\nactual:%v", test.reason, test.expected, gameMap)
+		a.startHandler(&testWriter{}, test.input)
+		if !gameEqual(test.expected, store.seats) {
+			t.Errorf("%s not equal:\nexpected:%v\nactual:%v", test.reason, test.expected, store.seats)
 		}
 	}
 
 }
 
 func TestWaitHandler(t *testing.T) {
+	defer sequentialIDs()()
 	r, _ := http.NewRequest("GET", "/", nil)
-	w3 := testWriter{}
-	w4 := testWriter{}
-	startHandler(&w3, r)
-	if `{"ID":3,"color":"Black"}` != string(w3.content) {
-		t.Errorf("Wait handler test %s not equal to expected id 3", string(w3.content))
+	a := newAPI(NewMemoryStore())
+	w1 := testWriter{}
+	w2 := testWriter{}
+	a.startHandler(&w1, r)
+	if `{"ID":"1","color":"Black","game":1}` != string(w1.content) {
+		t.Errorf("Wait handler test %s not equal to expected id 1", string(w1.content))
 	}
-	startHandler(&w4, r)
-	if `{"ID":4,"color":"White"}` != string(w4.content) {
-		t.Errorf("Wait handler test %s not equal to expected id 4", string(w4.content))
+	a.startHandler(&w2, r)
+	if `{"ID":"2","color":"White","game":1}` != string(w2.content) {
+		t.Errorf("Wait handler test %s not equal to expected id 2", string(w2.content))
 	}
 	wg.Add(1)
-	go gameWait(&w4, 4)
+	go gameWait(a, &w2, "2")
 	time.Sleep(1 * time.Second)
-	playMove(&w3, 3, "[1,1]")
+	playMove(a, &w1, "1", "[1,1]")
 	wg.Wait()
-	if `"valid"` != string(w3.content) {
-		t.Errorf("Wait handler test move 3 not valid: %s", string(w3.content))
+	if `"valid"` != string(w1.content) {
+		t.Errorf("Wait handler test move 1 not valid: %s", string(w1.content))
 	}
-	if `"go bot go"` != string(w4.content) {
-		t.Errorf("Wait handler test wait 4 out: %s", string(w4.content))
+	if `"go bot go"` != string(w2.content) {
+		t.Errorf("Wait handler test wait 2 out: %s", string(w2.content))
 	}
 
 	wg.Add(1)
-	go gameWait(&w3, 3)
+	go gameWait(a, &w1, "1")
 	time.Sleep(1 * time.Second)
-	playMove(&w4, 4, "[2,2]")
+	playMove(a, &w2, "2", "[2,2]")
 	wg.Wait()
-	if `"valid"` != string(w4.content) {
-		t.Errorf("Wait handler test move 4 not valid: %s", string(w4.content))
+	if `"valid"` != string(w2.content) {
+		t.Errorf("Wait handler test move 2 not valid: %s", string(w2.content))
 	}
-	if `"go bot go"` != string(w3.content) {
-		t.Errorf("Wait handler test wait 3 out: %s", string(w3.content))
+	if `"go bot go"` != string(w1.content) {
+		t.Errorf("Wait handler test wait 1 out: %s", string(w1.content))
 	}
 
 }
@@ -127,15 +152,259 @@ func gameEqual(lhs, rhs map[GameID]*Game) bool {
 	return true
 }
 
-func gameWait(w http.ResponseWriter, id GameID) {
-	path := fmt.Sprintf("/%d/wait/", id)
+func gameWait(a *api, w http.ResponseWriter, id GameID) {
+	path := fmt.Sprintf("/%s/wait/", id)
 	r, _ := http.NewRequest("GET", path, nil)
-	playHandler(w, r)
+	a.playHandler(w, r)
 	wg.Done()
 }
 
-func playMove(w http.ResponseWriter, id GameID, move string) {
-	path := fmt.Sprintf("/%d/move/", id)
+func playMove(a *api, w http.ResponseWriter, id GameID, move string) {
+	path := fmt.Sprintf("/%s/move/", id)
 	r, _ := http.NewRequest("POST", path, bytes.NewBufferString(move))
-	playHandler(w, r)
+	a.playHandler(w, r)
+}
+
+func TestHistoryHandler(t *testing.T) {
+	defer sequentialIDs()()
+	r, _ := http.NewRequest("GET", "/", nil)
+	a := newAPI(NewMemoryStore())
+	a.startHandler(&testWriter{}, r)
+	a.startHandler(&testWriter{}, r)
+	w := &testWriter{}
+	playMove(a, w, "1", "[1,1]")
+	playMove(a, w, "2", "[]")
+	r, _ = http.NewRequest("GET", "/2/history", nil)
+	a.playHandler(w, r)
+	var record game.Record
+	if err := json.Unmarshal(w.content, &record); err != nil {
+		t.Fatalf("unable to decode history %s: '%s'", string(w.content), err)
+	}
+	expected := []game.Turn{
+		{Move: game.Move{Player: game.Black, Position: game.Position{X: 1, Y: 1}}},
+		{Move: game.Move{Player: game.White}, Pass: true},
+	}
+	if record.Size != 19 || !reflect.DeepEqual(record.Turns, expected) {
+		t.Errorf("unexpected history %+v", record)
+	}
+	if _, err := game.Replay(record, 2); err != nil {
+		t.Errorf("unable to replay history: '%s'", err)
+	}
+}
+
+func TestUndoHandler(t *testing.T) {
+	defer sequentialIDs()()
+	r, _ := http.NewRequest("GET", "/", nil)
+	a := newAPI(NewMemoryStore())
+	a.startHandler(&testWriter{}, r)
+	a.startHandler(&testWriter{}, r)
+	w := &testWriter{}
+	playMove(a, w, "1", "[0,1]")
+	playMove(a, w, "2", "[0,0]")
+	playMove(a, w, "1", "[1,0]")
+
+	tests := []struct {
+		id       GameID
+		query    string
+		response string
+	}{
+		{"1", "?answer=accept", `"No undo requested by opponent"`},
+		{"2", "", `"requested"`},
+		{"2", "?answer=accept", `"No undo requested by opponent"`},
+		{"1", "?answer=reject", `"rejected"`},
+		{"1", "?answer=accept", `"No undo requested by opponent"`},
+		{"1", "", `"requested"`},
+		{"2", "?answer=maybe", `"maybe is not a valid undo answer"`},
+		{"2", "?answer=accept", `"undone"`},
+	}
+	for i, test := range tests {
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/%s/undo%s", test.id, test.query), nil)
+		a.playHandler(w, r)
+		if string(w.content) != test.response {
+			t.Errorf("%d: expected %s, got %s", i, test.response, string(w.content))
+		}
+	}
+
+	// The capture of 0,0 was taken back, so Black can play elsewhere
+	g, _ := a.store.Lookup("1")
+	if s := g.state.Public(); s.Board[0][0] != game.White || s.Black.Captured != 0 || s.CurrentPlayer != game.Black {
+		t.Errorf("undo did not restore the game: %+v", s)
+	}
+	playMove(a, w, "1", "[2,2]")
+	if `"valid"` != string(w.content) {
+		t.Errorf("expected Black to move after undo, got %s", string(w.content))
+	}
+}
+
+func TestStartHandicap(t *testing.T) {
+	defer sequentialIDs()()
+	a := newAPI(NewMemoryStore())
+	w := &testWriter{}
+	r, _ := http.NewRequest("GET", "/?size=9&handicap=10", nil)
+	a.startHandler(w, r)
+	if len(a.lobby) != 0 {
+		t.Fatalf("expected invalid handicap to not start a game, got %s", string(w.content))
+	}
+	r, _ = http.NewRequest("GET", "/?size=9&handicap=2&placement=free", nil)
+	a.startHandler(w, r)
+	a.startHandler(w, r)
+	tests := []struct {
+		id       GameID
+		move     string
+		expected string
+	}{
+		{"2", "[4,4]", `"Wrong player for move"`},
+		{"1", "[2,2]", `"valid"`},
+		{"1", "[]", `"Black must place handicap stones"`},
+		{"1", "[6,6]", `"valid"`},
+		{"1", "[4,4]", `"Wrong player for move"`},
+		{"2", "[4,4]", `"valid"`},
+	}
+	for _, test := range tests {
+		playMove(a, w, test.id, test.move)
+		if string(w.content) != test.expected {
+			t.Errorf("player %s move %s: expected %s, got %s", test.id, test.move, test.expected, string(w.content))
+		}
+	}
+	g, _ := a.store.Lookup("1")
+	if rules := g.state.Rules(); rules.Komi != handicapKomi || rules.Handicap.Stones != 2 {
+		t.Errorf("expected handicap rules, got %+v", rules)
+	}
+}
+
+func TestResignHandler(t *testing.T) {
+	defer sequentialIDs()()
+	r, _ := http.NewRequest("GET", "/", nil)
+	a := newAPI(NewMemoryStore())
+	a.startHandler(&testWriter{}, r)
+	a.startHandler(&testWriter{}, r)
+	w := &testWriter{}
+	playMove(a, w, "1", "[1,1]")
+
+	// White resigns while it is their turn, and Black cannot resign after
+	r, _ = http.NewRequest("POST", "/2/resign", nil)
+	a.playHandler(w, r)
+	if `{"winner":"Black","reason":"resignation"}` != string(w.content) {
+		t.Errorf("unexpected resignation result %s", string(w.content))
+	}
+	r, _ = http.NewRequest("POST", "/1/resign", nil)
+	a.playHandler(w, r)
+	if !gameIsOver(w.content) {
+		t.Errorf("expected game to be over, got %s", string(w.content))
+	}
+	g, _ := a.store.Lookup("1")
+	if h := g.state.History(); len(h) != 2 || !h[1].Resign {
+		t.Errorf("expected resignation in history, got %v", h)
+	}
+}
+
+func TestFinishedGame(t *testing.T) {
+	defer sequentialIDs()()
+	r, _ := http.NewRequest("GET", "/", nil)
+	a := newAPI(NewMemoryStore())
+	a.startHandler(&testWriter{}, r)
+	a.startHandler(&testWriter{}, r)
+	w := &testWriter{}
+	r, _ = http.NewRequest("GET", "/1/result", nil)
+	a.playHandler(w, r)
+	if `"Game is not over"` != string(w.content) {
+		t.Errorf("expected no result while playing, got %s", string(w.content))
+	}
+	playMove(a, w, "1", "[1,1]")
+	playMove(a, w, "2", "[]")
+	playMove(a, w, "1", "[]")
+	if !gameIsOver(w.content) {
+		t.Fatalf("expected the second pass to end the game, got %s", string(w.content))
+	}
+
+	// The final position can still be fetched
+	r, _ = http.NewRequest("GET", "/2/state", nil)
+	a.playHandler(w, r)
+	var s game.PublicState
+	if err := json.Unmarshal(w.content, &s); err != nil {
+		t.Fatalf("unable to decode final state %s: '%s'", string(w.content), err)
+	}
+	if s.Board[1][1] != game.Black || s.Result == nil || s.Result.Winner != game.Black {
+		t.Errorf("unexpected final state %s", string(w.content))
+	}
+	r, _ = http.NewRequest("GET", "/2/result", nil)
+	a.playHandler(w, r)
+	var f final
+	if err := json.Unmarshal(w.content, &f); err != nil {
+		t.Fatalf("unable to decode result %s: '%s'", string(w.content), err)
+	}
+	if f.Winner != game.Black || f.Reason != game.ScoreReason || f.Score.Black.Stones != 1 || f.Board[1][1] != game.Black {
+		t.Errorf("unexpected result %+v", f)
+	}
+	r, _ = http.NewRequest("GET", "/2/history", nil)
+	a.playHandler(w, r)
+	var record game.Record
+	if err := json.Unmarshal(w.content, &record); err != nil || len(record.Turns) != 3 {
+		t.Errorf("expected 3 turns in history, got %s", string(w.content))
+	}
+	for _, action := range []string{"wait", "undo", "resign"} {
+		r, _ = http.NewRequest("POST", "/2/"+action, nil)
+		a.playHandler(w, r)
+		if !gameIsOver(w.content) {
+			t.Errorf("expected %s to report the game is over, got %s", action, string(w.content))
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	defer sequentialIDs()()
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(ts.URL + "/api/v2/game/start/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	tests := []struct {
+		path    string
+		move    string
+		status  int
+		code    ErrorCode
+		details string
+	}{
+		{"/api/v2/game/play/2/move", "[1,1]", http.StatusConflict, CodeNotYourTurn, ""},
+		{"/api/v2/game/play/1/move", "[1]", http.StatusBadRequest, CodeBadCoordinates, ""},
+		{"/api/v2/game/play/1/move", "[19,1]", http.StatusUnprocessableEntity, CodeOutOfBounds, ""},
+		{"/api/v2/game/play/3/move", "[1,1]", http.StatusNotFound, CodeUnknownID, ""},
+		{"/api/v2/game/play/1/jump", "[1,1]", http.StatusNotFound, CodeUnknownAction, ""},
+		{"/api/v2/game/play/1/move", "[1,1]", http.StatusOK, "", ""},
+		{"/api/v2/game/play/2/move", "[1,1]", http.StatusUnprocessableEntity, CodeSpotNotEmpty, ""},
+		{"/api/v2/game/play/2/resign", "", http.StatusOK, "", ""},
+		{"/api/v2/game/play/1/move", "[2,2]", http.StatusConflict, CodeGameOver, `{"winner":"Black","reason":"resignation"}`},
+	}
+	for _, test := range tests {
+		resp, err := http.Post(ts.URL+test.path, "application/json", bytes.NewBufferString(test.move))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var e struct {
+			Code    ErrorCode       `json:"code"`
+			Message string          `json:"message"`
+			Details json.RawMessage `json:"details"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		resp.Body.Close()
+		if resp.StatusCode != test.status || e.Code != test.code || string(e.Details) != test.details {
+			t.Errorf("%s %s: expected %d %s %s, got %d %+v", test.path, test.move, test.status, test.code, test.details, resp.StatusCode, e)
+		}
+	}
+
+	// v1 still sends the message
+	resp, err := http.Post(ts.URL+"/api/v1/game/play/3/move", "application/json", bytes.NewBufferString("[1,1]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message string
+	json.NewDecoder(resp.Body).Decode(&message)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || message != "id 3 is not registered" {
+		t.Errorf("expected v1 message, got %d %q", resp.StatusCode, message)
+	}
 }
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// Settings are chosen by the player opening a game, and players are only paired with equal settings
+type Settings struct {
+	Size  int        `json:"size"`
+	Rules game.Rules `json:"rules"`
+	// Rated games count towards players' ratings
+	Rated bool `json:"rated"`
+}
+
+// DefaultSettings are used for anything not given when a game is opened
+var DefaultSettings = Settings{Size: 19, Rules: game.DefaultRules}
+
+// Boards are between minSize and maxSize, which is the largest board GTP can play
+const (
+	minSize = 2
+	maxSize = 25
+)
+
+// handicapKomi is the komi given to White in handicap games
+const handicapKomi = 0.5
+
+// Query encodes the settings as the query of a start/ or lobby/ request
+func (s Settings) Query() url.Values {
+	v := url.Values{}
+	v.Set("size", strconv.Itoa(s.Size))
+	v.Set("komi", strconv.FormatFloat(s.Rules.Komi, 'f', -1, 64))
+	v.Set("scoring", s.Rules.Scoring.String())
+	v.Set("ko", s.Rules.Ko.String())
+	if s.Rules.PassStones {
+		v.Set("passstones", "true")
+	}
+	if h := s.Rules.Handicap; h.Stones > 0 {
+		v.Set("handicap", strconv.Itoa(h.Stones))
+		v.Set("placement", h.Placement.String())
+	}
+	if tc := s.Rules.Time; tc.System != game.NoTime {
+		v.Set("time", tc.System.String())
+		v.Set("main", tc.Main.String())
+		v.Set("increment", tc.Increment.String())
+		v.Set("period", tc.Period.String())
+		v.Set("periods", strconv.Itoa(tc.Periods))
+		v.Set("stones", strconv.Itoa(tc.Stones))
+	}
+	if s.Rated {
+		v.Set("rated", "true")
+	}
+	return v
+}
+
+// Check returns an error if a game cannot be played with the settings
+func (s Settings) Check() error {
+	if s.Size < minSize || s.Size > maxSize {
+		return fmt.Errorf("%d is not a valid size, boards are %d-%d", s.Size, minSize, maxSize)
+	}
+	return s.Rules.Check(s.Size)
+}
+
+// parseSettings reads the settings of a new game, starting from DefaultSettings
+func parseSettings(v url.Values) (Settings, error) {
+	s := DefaultSettings
+	if size := v.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < minSize || n > maxSize {
+			return s, fmt.Errorf("%s is not a valid size, boards are %d-%d", size, minSize, maxSize)
+		}
+		s.Size = n
+	}
+	var err error
+	if s.Rules, err = parseRules(v, s.Size); err != nil {
+		return s, err
+	}
+	s.Rated = v.Get("rated") == "true"
+	return s, nil
+}
+
+// parseRules reads the rules of a new game, such as komi=6.5&scoring=territory&handicap=2
+func parseRules(v url.Values, size int) (game.Rules, error) {
+	rules := game.DefaultRules
+	if h := v.Get("handicap"); h != "" {
+		n, err := strconv.Atoi(h)
+		if err != nil {
+			return rules, fmt.Errorf("%s is not a valid handicap", h)
+		}
+		rules.Handicap.Stones = n
+	}
+	switch p := v.Get("placement"); p {
+	case "", "fixed":
+	case "free":
+		rules.Handicap.Placement = game.FreePlacement
+	default:
+		return rules, fmt.Errorf("%s is not a valid placement", p)
+	}
+	if rules.Handicap.Stones > 0 {
+		rules.Komi = handicapKomi
+	}
+	if k := v.Get("komi"); k != "" {
+		komi, err := strconv.ParseFloat(k, 64)
+		if err != nil || math.IsNaN(komi) || math.IsInf(komi, 0) {
+			return rules, fmt.Errorf("%s is not a valid komi", k)
+		}
+		rules.Komi = komi
+	}
+	switch sc := v.Get("scoring"); sc {
+	case "", "area":
+	case "territory":
+		rules.Scoring = game.TerritoryScoring
+	default:
+		return rules, fmt.Errorf("%s is not a valid scoring", sc)
+	}
+	switch ko := v.Get("ko"); ko {
+	case "":
+	case "simple":
+		rules.Ko = game.SimpleKo
+	case "positional":
+		rules.Ko = game.PositionalSuperko
+	case "situational":
+		rules.Ko = game.SituationalSuperko
+	default:
+		return rules, fmt.Errorf("%s is not a valid ko rule", ko)
+	}
+	rules.PassStones = v.Get("passstones") == "true"
+	tc, err := parseTimeControl(v)
+	if err != nil {
+		return rules, err
+	}
+	rules.Time = tc
+	return rules, rules.Check(size)
+}
+
+// parseTimeControl reads the time control of a new game, such as
+// time=byoyomi&main=10m&period=30s&periods=5
+func parseTimeControl(v url.Values) (game.TimeControl, error) {
+	var tc game.TimeControl
+	var err error
+	if tc.System, err = game.ParseTimeSystem(v.Get("time")); err != nil {
+		return tc, err
+	}
+	for _, d := range []struct {
+		name string
+		d    *time.Duration
+	}{
+		{"main", &tc.Main},
+		{"increment", &tc.Increment},
+		{"period", &tc.Period},
+	} {
+		if *d.d, err = parseDuration(v.Get(d.name)); err != nil {
+			return tc, fmt.Errorf("%s is not a valid %s time", v.Get(d.name), d.name)
+		}
+	}
+	for _, n := range []struct {
+		name string
+		n    *int
+	}{
+		{"periods", &tc.Periods},
+		{"stones", &tc.Stones},
+	} {
+		if s := v.Get(n.name); s != "" {
+			if *n.n, err = strconv.Atoi(s); err != nil {
+				return tc, fmt.Errorf("%s is not a valid number of %s", s, n.name)
+			}
+		}
+	}
+	return tc, nil
+}
+
+// parseDuration reads a duration such as 90s or 1m30s, or a number of seconds
+func parseDuration(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(s * float64(time.Second)), nil
+	}
+	return time.ParseDuration(v)
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// liveGame describes a game being played in the list of live games
+type liveGame struct {
+	Game uint64 `json:"game"`
+	// Black and White are the names of registered players, and empty for anonymous players
+	Black         string     `json:"black,omitempty"`
+	White         string     `json:"white,omitempty"`
+	Size          int        `json:"size"`
+	Turns         int        `json:"turns"`
+	CurrentPlayer game.Color `json:"currentplayer"`
+	Rated         bool       `json:"rated"`
+}
+
+// spectatorGame is the state of a game sent to spectators
+type spectatorGame struct {
+	publicGame
+	Black string `json:"black,omitempty"`
+	White string `json:"white,omitempty"`
+	// Turns is the number of turns played, which spectators wait for to change
+	Turns int `json:"turns"`
+}
+
+// names are the names of the registered players seated in g, and must be called with g.mu held
+func (a *api) names(g *Game) (black, white string) {
+	for c, name := range map[game.Color]*string{game.Black: &black, game.White: &white} {
+		if account := g.accounts[c]; account != 0 {
+			if p, err := a.store.Player(account); err == nil {
+				*name = p.Name
+			}
+		}
+	}
+	return black, white
+}
+
+// lookupGame finds a game by the number in a request path
+func (a *api) lookupGame(s string) (*Game, error) {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err == nil {
+		games, err := a.store.List()
+		if err != nil {
+			return nil, Error{Code: CodeInternal, Message: err.Error()}
+		}
+		for _, g := range games {
+			if g.id == n {
+				return g, nil
+			}
+		}
+	}
+	return nil, newError(CodeUnknownGame, "Game %s does not exist", s)
+}
+
+// gamesHandler lets anyone watch games by their number, without playing them.
+// games/ lists the games being played, games/<game> is the state of a game,
+// games/<game>/wait?turns=<n> returns it once it has a number of turns other than n,
+// games/<game>/history, games/<game>/result and games/<game>/events are as for players,
+// and games/<game>/ws is the game's channel, on which spectators can only chat.
+func (a *api) gamesHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] == "" {
+		a.liveHandler(w, r)
+		return
+	}
+	g, err := a.lookupGame(parts[0])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	switch action {
+	case "":
+		a.spectateHandler(g, w, r)
+	case "wait":
+		a.watchHandler(g, w, r)
+	case "history":
+		g.historyHandler(w, r)
+	case "result":
+		g.resultHandler(w, r)
+	case "ws":
+		a.channelHandler(g, w, r, "")
+	case "events":
+		a.streamHandler(g, w, r)
+	default:
+		writeError(w, r, newError(CodeUnknownAction, "%s is not a valid spectator action", action))
+	}
+}
+
+// liveHandler lists the games with both players seated that are not over, oldest first
+func (a *api) liveHandler(w http.ResponseWriter, r *http.Request) {
+	games, err := a.store.List()
+	if err != nil {
+		writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+		return
+	}
+	live := []liveGame{}
+	for _, g := range games {
+		g.mu.Lock()
+		if len(g.players) == 2 && !g.state.Over() {
+			black, white := a.names(g)
+			live = append(live, liveGame{g.id, black, white, g.state.Size(), len(g.state.History()), g.state.Public().CurrentPlayer, g.rated})
+		}
+		g.mu.Unlock()
+	}
+	writeJSON(w, live)
+}
+
+func (a *api) spectateHandler(g *Game, w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	black, white := a.names(g)
+	writeJSON(w, spectatorGame{a.public(g), black, white, len(g.state.History())})
+}
+
+// watchHandler waits until the game has a number of turns other than turns=<n>, or is over,
+// and writes its state. Without turns it waits for the next turn, and it gives up as wait does.
+func (a *api) watchHandler(g *Game, w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	turns := len(g.state.History())
+	g.mu.Unlock()
+	if v := r.FormValue("turns"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a valid number of turns", v))
+			return
+		}
+		turns = n
+	}
+	if !g.awaitRequest(w, r, func() bool { return g.state.Over() || len(g.state.History()) != turns }) {
+		return
+	}
+	a.spectateHandler(g, w, r)
+}
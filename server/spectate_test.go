@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+func TestSpectate(t *testing.T) {
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	ann := register(t, ts.URL, "name=ann").Key
+	var b, w seated
+	getJSON(t, ts.URL+"/api/v2/game/start/?size=9", ann, &b)
+	getJSON(t, ts.URL+"/api/v2/game/start/?size=9", "", &w)
+	// A game still waiting for White is not live
+	getJSON(t, ts.URL+"/api/v2/game/start/", "", &seated{})
+
+	var live []liveGame
+	getJSON(t, ts.URL+"/api/v2/games/", "", &live)
+	if len(live) != 1 || live[0].Game != b.Game || live[0].Black != "ann" || live[0].White != "" || live[0].Size != 9 {
+		t.Fatalf("expected game %d between ann and an anonymous player, got %+v", b.Game, live)
+	}
+	root := ts.URL + "/api/v2/games/" + strconv.FormatUint(b.Game, 10)
+
+	watched := make(chan spectatorGame)
+	go func() {
+		var s spectatorGame
+		getJSON(t, root+"/wait?turns=0", "", &s)
+		watched <- s
+	}()
+	select {
+	case <-watched:
+		t.Fatal("expected the spectator to wait for the first move")
+	case <-time.After(50 * time.Millisecond):
+	}
+	r, _ := http.NewRequest("POST", ts.URL+"/api/v2/game/play/"+string(b.ID)+"/move", bytes.NewBufferString("[2, 3]"))
+	r.Header.Set(KeyHeader, ann)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("unable to move: %v %v", resp, err)
+	}
+	resp.Body.Close()
+	select {
+	case s := <-watched:
+		if s.Turns != 1 || s.Board[2][3] != game.Black || s.CurrentPlayer != game.White || s.Black != "ann" {
+			t.Errorf("unexpected state after the first move %+v", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("spectator was not woken by the move")
+	}
+
+	// Spectators cannot play
+	var e Error
+	if status := getJSON(t, root+"/move", "", &e); status != http.StatusNotFound || e.Code != CodeUnknownAction {
+		t.Errorf("expected spectators to be refused moves, got %d %+v", status, e)
+	}
+	var result game.Result
+	getJSON(t, ts.URL+"/api/v2/game/play/"+string(w.ID)+"/resign", "", &result)
+	var s spectatorGame
+	getJSON(t, root+"/wait?turns=1", "", &s)
+	if s.Result == nil || s.Result.Winner != game.Black || s.Game != b.Game {
+		t.Errorf("expected Black to have won, got %+v", s)
+	}
+	var record game.Record
+	if getJSON(t, root+"/history", "", &record); len(record.Turns) != 2 {
+		t.Errorf("expected a move and a resignation, got %+v", record.Turns)
+	}
+	getJSON(t, ts.URL+"/api/v2/games/", "", &live)
+	if len(live) != 0 {
+		t.Errorf("expected no live games, got %+v", live)
+	}
+	for _, path := range []string{"9", "x"} {
+		if status := getJSON(t, ts.URL+"/api/v2/games/"+path, "", &e); status != http.StatusNotFound || e.Code != CodeUnknownGame {
+			t.Errorf("%s: expected %s, got %d %+v", path, CodeUnknownGame, status, e)
+		}
+	}
+}
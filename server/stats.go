@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+)
+
+// counts are the statistics of the games played since the server started
+type counts struct {
+	Games    int `json:"games"`
+	Finished int `json:"finished"`
+	Moves    int `json:"moves"`
+	Passes   int `json:"passes"`
+	Undos    int `json:"undos"`
+	// Wins counts the finished games won by each color, and Reasons how they ended
+	Wins    map[string]int `json:"wins"`
+	Reasons map[string]int `json:"reasons"`
+}
+
+// stats counts the events published on the bus
+type stats struct {
+	mu     sync.Mutex
+	counts counts
+}
+
+func newStats() *stats {
+	return &stats{counts: counts{Wins: map[string]int{}, Reasons: map[string]int{}}}
+}
+
+// count is subscribed to the api's bus
+func (s *stats) count(g *Game, e GameEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &s.counts
+	switch e.Kind {
+	case GameCreated:
+		c.Games++
+	case MovePlayed:
+		c.Moves++
+	case Passed:
+		c.Passes++
+	case TurnUndone:
+		c.Undos++
+	case GameEnded:
+		c.Finished++
+		c.Wins[e.Result.Winner.String()]++
+		c.Reasons[e.Result.Reason.String()]++
+	}
+}
+
+// snapshot copies the counts
+func (s *stats) snapshot() counts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.counts
+	c.Wins, c.Reasons = map[string]int{}, map[string]int{}
+	for k, v := range s.counts.Wins {
+		c.Wins[k] = v
+	}
+	for k, v := range s.counts.Reasons {
+		c.Reasons[k] = v
+	}
+	return c
+}
+
+// statsHandler writes the statistics of the games played since the server started
+func (a *api) statsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, a.stats.snapshot())
+}
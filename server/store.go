@@ -0,0 +1,385 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/gophergala2016/gobotgo/game"
+)
+
+// Store keeps games and the players seated in them. Implementations must be safe for concurrent use.
+type Store interface {
+	// Create adds a new game with no players, listed as l while it waits in the lobby
+	Create(s Settings, l Listing) (*Game, error)
+	// Join seats a new player of color c in g and returns their GameID.
+	// account is the registered player taking the seat, or 0 for anyone.
+	Join(g *Game, c game.Color, account uint64) (GameID, error)
+	// Lookup finds the game a player is seated in
+	Lookup(id GameID) (*Game, error)
+	// Record keeps an event of a game, other than its creation and players joining,
+	// which Create and Join keep
+	Record(e GameEvent) error
+	// Events returns the events of game n, oldest first, which Rebuild replays
+	Events(n uint64) ([]GameEvent, error)
+	// List returns every game, oldest first
+	List() ([]*Game, error)
+	// Register adds a player with the hash of their API key, and returns them with their ID
+	Register(p Player, keyHash string) (Player, error)
+	// Player finds a registered player
+	Player(id uint64) (Player, error)
+	// PlayerNamed finds a registered player by name
+	PlayerNamed(name string) (Player, error)
+	// Authenticate finds the player with the hash of an API key
+	Authenticate(keyHash string) (Player, error)
+	// Rate records players' new ratings after a rated game
+	Rate(changes ...RatingChange) error
+	// Ratings returns a player's rating changes, oldest first
+	Ratings(player uint64) ([]RatingChange, error)
+	// Current returns the latest rating change of every player rated on boards of size
+	Current(size int) ([]RatingChange, error)
+}
+
+// MemoryStore keeps games in memory only
+type MemoryStore struct {
+	mu    sync.Mutex
+	games []*Game
+	// events are the events of each game, in the order of games
+	events [][]GameEvent
+	seats  map[GameID]*Game
+	// accounts are the registered players, found by the hash of their key or their name
+	accounts []Player
+	keys     map[string]uint64
+	names    map[string]uint64
+	// ratings are every player's rating changes, and current their latest on each board size
+	ratings map[uint64][]RatingChange
+	current map[int]map[uint64]RatingChange
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		seats:   map[GameID]*Game{},
+		keys:    map[string]uint64{},
+		names:   map[string]uint64{},
+		ratings: map[uint64][]RatingChange{},
+		current: map[int]map[uint64]RatingChange{},
+	}
+}
+
+func (m *MemoryStore) Create(s Settings, l Listing) (*Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, err := game.New(s.Size, Pieces, s.Rules)
+	if err != nil {
+		return nil, err
+	}
+	g := newGame(uint64(len(m.games)+1), state)
+	g.rated = s.Rated
+	g.listing = l
+	m.games = append(m.games, g)
+	m.events = append(m.events, []GameEvent{{Kind: GameCreated, Game: g.id, Settings: &s}})
+	return g, nil
+}
+
+func (m *MemoryStore) Join(g *Game, c game.Color, account uint64) (GameID, error) {
+	id := newGameID()
+	m.seat(g, id, c, account)
+	return id, nil
+}
+
+// seat gives player id the seat c in g
+func (m *MemoryStore) seat(g *Game, id GameID, c game.Color, account uint64) {
+	m.mu.Lock()
+	m.seats[id] = g
+	m.events[g.id-1] = append(m.events[g.id-1], GameEvent{Kind: PlayerJoined, Game: g.id, Player: id, Color: c, Account: account})
+	m.mu.Unlock()
+	g.seat(id, c, account)
+}
+
+func (m *MemoryStore) Lookup(id GameID) (*Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.seats[id]
+	if !ok {
+		return nil, newError(CodeUnknownID, "id %s is not registered", id)
+	}
+	return g, nil
+}
+
+func (m *MemoryStore) Record(e GameEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e.Game < 1 || e.Game > uint64(len(m.games)) {
+		return fmt.Errorf("unknown game %d", e.Game)
+	}
+	m.events[e.Game-1] = append(m.events[e.Game-1], e)
+	return nil
+}
+
+func (m *MemoryStore) Events(n uint64) ([]GameEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n < 1 || n > uint64(len(m.games)) {
+		return nil, newError(CodeUnknownGame, "Game %d does not exist", n)
+	}
+	return append([]GameEvent{}, m.events[n-1]...), nil
+}
+
+func (m *MemoryStore) List() ([]*Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Game{}, m.games...), nil
+}
+
+func (m *MemoryStore) Register(p Player, keyHash string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.names[p.Name]; ok {
+		return p, newError(CodeNameTaken, "%s is already registered", p.Name)
+	}
+	p.ID = uint64(len(m.accounts) + 1)
+	m.accounts = append(m.accounts, p)
+	m.keys[keyHash] = p.ID
+	m.names[p.Name] = p.ID
+	return p, nil
+}
+
+func (m *MemoryStore) Player(id uint64) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id < 1 || id > uint64(len(m.accounts)) {
+		return Player{}, newError(CodeUnknownPlayer, "player %d is not registered", id)
+	}
+	return m.accounts[id-1], nil
+}
+
+func (m *MemoryStore) PlayerNamed(name string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.names[name]
+	if !ok {
+		return Player{}, newError(CodeUnknownPlayer, "%s is not registered", name)
+	}
+	return m.accounts[id-1], nil
+}
+
+func (m *MemoryStore) Authenticate(keyHash string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.keys[keyHash]
+	if !ok {
+		return Player{}, newError(CodeUnauthorized, "API key is not registered")
+	}
+	return m.accounts[id-1], nil
+}
+
+func (m *MemoryStore) Rate(changes ...RatingChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range changes {
+		m.ratings[c.Player] = append(m.ratings[c.Player], c)
+		if m.current[c.Size] == nil {
+			m.current[c.Size] = map[uint64]RatingChange{}
+		}
+		m.current[c.Size][c.Player] = c
+	}
+	return nil
+}
+
+func (m *MemoryStore) Ratings(player uint64) ([]RatingChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]RatingChange{}, m.ratings[player]...), nil
+}
+
+// Current returns the rating changes in the order players registered
+func (m *MemoryStore) Current(size int) ([]RatingChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var current []RatingChange
+	for _, p := range m.accounts {
+		if c, ok := m.current[size][p.ID]; ok {
+			current = append(current, c)
+		}
+	}
+	return current, nil
+}
+
+// FileStore keeps games in memory and appends every event and player to a file of JSON lines,
+// which is replayed when the store is opened.
+type FileStore struct {
+	*MemoryStore
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// record is a line of a FileStore
+type record struct {
+	Op    string      `json:"op"`
+	Game  uint64      `json:"game"`
+	Size  int         `json:"size,omitempty"`
+	Rules *game.Rules `json:"rules,omitempty"`
+	Rated bool        `json:"rated,omitempty"`
+	// Listing is how a created game is shown in the lobby
+	Listing *Listing   `json:"listing,omitempty"`
+	Player  GameID     `json:"player,omitempty"`
+	Color   game.Color `json:"color,omitempty"`
+	// Account is the registered player taking a seat
+	Account uint64     `json:"account,omitempty"`
+	Turn    *game.Turn `json:"turn,omitempty"`
+	// Result is how the game ended
+	Result *game.Result `json:"result,omitempty"`
+	// Registered is a new player, with the hash of their key
+	Registered *Player `json:"registered,omitempty"`
+	Key        string  `json:"key,omitempty"`
+	// Ratings are the rating changes after a rated game
+	Ratings []RatingChange `json:"ratings,omitempty"`
+}
+
+// The ops of records that are not game events, named by their EventKind
+const (
+	opRegister = "register"
+	opRate     = "rate"
+)
+
+// NewFileStore opens or creates the file at path and restores the games in it
+func NewFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		f:           f,
+		enc:         json.NewEncoder(f),
+	}
+	if err := s.restore(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("restoring %s: %s", path, err.Error())
+	}
+	return s, nil
+}
+
+func (s *FileStore) restore() error {
+	scanner := bufio.NewScanner(s.f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		if err := s.replay(r); err != nil {
+			return fmt.Errorf("line %d: %s", line, err.Error())
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *FileStore) replay(r record) error {
+	switch r.Op {
+	case string(GameCreated):
+		if r.Rules == nil {
+			return fmt.Errorf("game %d has no rules", r.Game)
+		}
+		var l Listing
+		if r.Listing != nil {
+			l = *r.Listing
+		}
+		_, err := s.MemoryStore.Create(Settings{r.Size, *r.Rules, r.Rated}, l)
+		return err
+	case opRegister:
+		if r.Registered == nil {
+			return fmt.Errorf("registered player is missing")
+		}
+		_, err := s.MemoryStore.Register(*r.Registered, r.Key)
+		return err
+	case opRate:
+		return s.MemoryStore.Rate(r.Ratings...)
+	}
+	if r.Game < 1 || r.Game > uint64(len(s.games)) {
+		return fmt.Errorf("unknown game %d", r.Game)
+	}
+	g := s.games[r.Game-1]
+	e := GameEvent{Kind: EventKind(r.Op), Game: r.Game, Turn: r.Turn, Result: r.Result}
+	switch e.Kind {
+	case PlayerJoined:
+		s.seat(g, r.Player, r.Color, r.Account)
+		return nil
+	case MovePlayed, Passed, Resigned, TimedOut:
+		if r.Turn == nil {
+			return fmt.Errorf("game %d turn is missing", r.Game)
+		}
+		e = turnEvent(r.Game, *r.Turn)
+	case TurnUndone, GameEnded:
+	default:
+		return fmt.Errorf("unknown op %q", r.Op)
+	}
+	if err := g.replay(e); err != nil {
+		return err
+	}
+	return s.MemoryStore.Record(e)
+}
+
+func (s *FileStore) write(r record) error {
+	return s.enc.Encode(r)
+}
+
+func (s *FileStore) Create(settings Settings, l Listing) (*Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.MemoryStore.Create(settings, l)
+	if err != nil {
+		return nil, err
+	}
+	r := record{Op: string(GameCreated), Game: g.id, Size: settings.Size, Rules: &settings.Rules, Rated: settings.Rated}
+	if l != (Listing{}) {
+		r.Listing = &l
+	}
+	return g, s.write(r)
+}
+
+func (s *FileStore) Join(g *Game, c game.Color, account uint64) (GameID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, err := s.MemoryStore.Join(g, c, account)
+	if err != nil {
+		return "", err
+	}
+	return id, s.write(record{Op: string(PlayerJoined), Game: g.id, Player: id, Color: c, Account: account})
+}
+
+func (s *FileStore) Record(e GameEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.MemoryStore.Record(e); err != nil {
+		return err
+	}
+	return s.write(record{Op: string(e.Kind), Game: e.Game, Turn: e.Turn, Result: e.Result})
+}
+
+func (s *FileStore) Register(p Player, keyHash string) (Player, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.MemoryStore.Register(p, keyHash)
+	if err != nil {
+		return p, err
+	}
+	return p, s.write(record{Op: opRegister, Registered: &p, Key: keyHash})
+}
+
+func (s *FileStore) Rate(changes ...RatingChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.MemoryStore.Rate(changes...); err != nil {
+		return err
+	}
+	return s.write(record{Op: opRate, Ratings: changes})
+}
+
+// Close closes the underlying file
+func (s *FileStore) Close() error {
+	return s.f.Close()
+}
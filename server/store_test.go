@@ -0,0 +1,225 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server/rating"
+)
+
+func TestConcurrentStart(t *testing.T) {
+	defer sequentialIDs()()
+	store := NewMemoryStore()
+	a := newAPI(store)
+	var start sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		start.Add(1)
+		go func() {
+			r, _ := http.NewRequest("GET", "/", nil)
+			a.startHandler(&testWriter{}, r)
+			start.Done()
+		}()
+	}
+	start.Wait()
+	games, _ := store.List()
+	if len(games) != 25 {
+		t.Fatalf("expected 25 games, got %d", len(games))
+	}
+	for _, g := range games {
+		if g.seated() != 2 {
+			t.Errorf("expected game %d to have 2 players, has %d", g.id, g.seated())
+		}
+	}
+}
+
+func TestFileStoreRestore(t *testing.T) {
+	defer sequentialIDs()()
+	dir, err := ioutil.TempDir("", "gobotgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.json")
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to create store: '%s'", err)
+	}
+	a := newAPI(s)
+	r, _ := http.NewRequest("GET", "/", nil)
+	for i := 0; i < 3; i++ {
+		a.startHandler(&testWriter{}, r)
+	}
+	w := &testWriter{}
+	playMove(a, w, "1", "[1,1]")
+	playMove(a, w, "2", "[2,2]")
+	playMove(a, w, "1", "[]")
+	playMove(a, w, "2", "[]")
+	if !gameIsOver(w.content) {
+		t.Fatalf("expected game to be over, got %s", string(w.content))
+	}
+	s.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to restore store: '%s'", err)
+	}
+	defer s.Close()
+	games, _ := s.List()
+	if len(games) != 2 {
+		t.Fatalf("expected 2 games, got %d", len(games))
+	}
+	g, err := s.Lookup("2")
+	if err != nil {
+		t.Fatalf("unable to find player 2: '%s'", err)
+	}
+	if c, _ := g.color("2"); c != game.White {
+		t.Errorf("expected player 2 to be White, got %s", c)
+	}
+	if !g.over() || len(g.state.History()) != 4 {
+		t.Errorf("expected finished game with 4 turns, got %v", g.state.History())
+	}
+	if _, err := s.Lookup("4"); err == nil {
+		t.Error("expected player 4 to not be registered")
+	}
+	events, _ := s.Events(1)
+	if kinds := eventKinds(events); kinds != "create join join move move pass pass end" {
+		t.Errorf("unexpected events restored %s", kinds)
+	}
+
+	// The third player is still waiting for an opponent, and new players get new ids
+	a = newAPI(s)
+	w = &testWriter{}
+	a.startHandler(w, r)
+	if `{"ID":"4","color":"White","game":2}` != string(w.content) {
+		t.Errorf("expected to join restored game, got %s", string(w.content))
+	}
+}
+
+func TestFileStoreUndo(t *testing.T) {
+	defer sequentialIDs()()
+	dir, err := ioutil.TempDir("", "gobotgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.json")
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to create store: '%s'", err)
+	}
+	a := newAPI(s)
+	r, _ := http.NewRequest("GET", "/", nil)
+	a.startHandler(&testWriter{}, r)
+	a.startHandler(&testWriter{}, r)
+	w := &testWriter{}
+	playMove(a, w, "1", "[1,1]")
+	playMove(a, w, "2", "[2,2]")
+	r, _ = http.NewRequest("POST", "/2/undo", nil)
+	a.playHandler(w, r)
+	r, _ = http.NewRequest("POST", "/1/undo?answer=accept", nil)
+	a.playHandler(w, r)
+	s.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to restore store: '%s'", err)
+	}
+	defer s.Close()
+	g, _ := s.Lookup("1")
+	if h := g.state.History(); len(h) != 1 {
+		t.Errorf("expected 1 turn after restoring undo, got %v", h)
+	}
+	a = newAPI(s)
+	playMove(a, w, "2", "[3,3]")
+	if `"valid"` != string(w.content) {
+		t.Errorf("expected White to move after restoring undo, got %s", string(w.content))
+	}
+}
+
+func TestFileStorePlayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gobotgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.json")
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to create store: '%s'", err)
+	}
+	bot, err := s.Register(Player{Name: "gobot", Kind: Bot}, hashKey("secret"))
+	if err != nil {
+		t.Fatalf("unable to register: '%s'", err)
+	}
+	g, _ := s.Create(DefaultSettings, Listing{})
+	id, _ := s.Join(g, game.Black, bot.ID)
+	change := RatingChange{Player: bot.ID, Size: 19, Game: g.id, Score: 1, Rating: rating.Initial, Games: 1}
+	s.Rate(change)
+	s.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to restore store: '%s'", err)
+	}
+	defer s.Close()
+	if p, err := s.Authenticate(hashKey("secret")); err != nil || p != bot {
+		t.Errorf("expected to authenticate %+v, got %+v '%v'", bot, p, err)
+	}
+	if _, err := s.Register(Player{Name: "gobot"}, hashKey("other")); err == nil {
+		t.Error("expected restored name to be taken")
+	}
+	if current, _ := s.Current(19); len(current) != 1 || current[0] != change {
+		t.Errorf("expected restored rating %+v, got %+v", change, current)
+	}
+	g, err = s.Lookup(id)
+	if err != nil || g.account(game.Black) != bot.ID {
+		t.Errorf("expected %s to be played by %d, got '%v'", id, bot.ID, err)
+	}
+}
+
+func TestFileStoreLobby(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gobotgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.json")
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to create store: '%s'", err)
+	}
+	ann, _ := s.Register(Player{Name: "ann"}, hashKey("ann"))
+	s.Register(Player{Name: "bob"}, hashKey("bob"))
+	a := newAPI(s)
+	listing := Listing{Name: "ann's game", Opponent: "bob"}
+	a.mu.Lock()
+	_, err = a.open(&OpenGame{account: ann.ID, Creator: ann.Name, Listing: listing, Settings: DefaultSettings})
+	a.mu.Unlock()
+	if err != nil {
+		t.Fatalf("unable to open game: '%s'", err)
+	}
+	s.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("unable to restore store: '%s'", err)
+	}
+	defer s.Close()
+	// The challenge is still only open to bob
+	a = newAPI(s)
+	if len(a.lobby) != 1 || a.lobby[0].Listing != listing || a.lobby[0].Creator != ann.Name {
+		t.Fatalf("expected %+v in the lobby, got %+v", listing, a.lobby)
+	}
+	if o := a.match(DefaultSettings, 0); o != nil {
+		t.Errorf("expected the challenge not to be matched, got %+v", o)
+	}
+}
@@ -0,0 +1,494 @@
+// Package tournament pairs players for round robin, Swiss, McMahon and knockout
+// tournaments, and ranks them with the SOS and SODOS tie-breakers.
+package tournament
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Format selects how players are paired
+type Format int
+
+const (
+	// RoundRobin pairs every player with every other player once
+	RoundRobin = Format(iota)
+	// Swiss pairs players with equal scores, for a fixed number of rounds
+	Swiss
+	// McMahon is Swiss, with players starting on a score set by their rating
+	McMahon
+	// Knockout pairs the winners of each round until one is left
+	Knockout
+)
+
+func (f Format) String() string {
+	switch f {
+	case Swiss:
+		return "swiss"
+	case McMahon:
+		return "mcmahon"
+	case Knockout:
+		return "knockout"
+	default:
+		return "roundrobin"
+	}
+}
+
+func (f Format) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
+func (f *Format) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	format, err := ParseFormat(s)
+	*f = format
+	return err
+}
+
+// ParseFormat reads a format by name, as written by String
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "roundrobin", "round-robin":
+		return RoundRobin, nil
+	case "swiss":
+		return Swiss, nil
+	case "mcmahon":
+		return McMahon, nil
+	case "knockout", "elimination":
+		return Knockout, nil
+	}
+	return RoundRobin, fmt.Errorf("%s is not a valid tournament format", s)
+}
+
+// Bye is the opponent of a player sitting out a round, which counts as a win
+const Bye = 0
+
+// Band is the rating difference worth a point of McMahon score
+const Band = 100
+
+var (
+	ErrEntrants = errors.New("Tournaments need at least two different entrants")
+	ErrRounds   = errors.New("Too many rounds for the number of entrants")
+	ErrRound    = errors.New("The round is still being played")
+	ErrOver     = errors.New("The tournament is over")
+	ErrPairing  = errors.New("No such pairing")
+	ErrPlayed   = errors.New("The pairing has already been played")
+	ErrScore    = errors.New("Scores are 0, 0.5 or 1")
+)
+
+// Entrant is a player in a tournament, with the rating they are seeded by
+type Entrant struct {
+	Player uint64  `json:"player"`
+	Rating float64 `json:"rating"`
+}
+
+// Pairing is a game in a round, or a bye when White is Bye
+type Pairing struct {
+	Round  int    `json:"round"`
+	Black  uint64 `json:"black"`
+	White  uint64 `json:"white"`
+	Played bool   `json:"played"`
+	// Score is Black's score once played: 1 for a win, 0.5 for a draw and 0 for a loss
+	Score float64 `json:"score"`
+}
+
+// Tournament is the entrants and pairings of a tournament
+type Tournament struct {
+	Format Format `json:"format"`
+	// Entrants are seeded in order, strongest first
+	Entrants []Entrant `json:"entrants"`
+	Rounds   int       `json:"rounds"`
+	// Round is the round being played, 0 before the first
+	Round int `json:"round"`
+	// Bar is the rating at and above which McMahon players start level,
+	// with a point less for every Band below it
+	Bar      float64   `json:"bar,omitempty"`
+	Pairings []Pairing `json:"pairings"`
+}
+
+// New starts a tournament between entrants, seeded in the order given. Rounds is only
+// used by Swiss and McMahon tournaments, which play enough rounds for a winner if it is 0.
+func New(format Format, entrants []Entrant, rounds int, bar float64) (*Tournament, error) {
+	n := len(entrants)
+	seen := map[uint64]bool{}
+	for _, e := range entrants {
+		if e.Player == Bye || seen[e.Player] {
+			return nil, ErrEntrants
+		}
+		seen[e.Player] = true
+	}
+	if n < 2 {
+		return nil, ErrEntrants
+	}
+	t := &Tournament{Format: format, Entrants: entrants, Bar: bar, Pairings: []Pairing{}}
+	switch format {
+	case RoundRobin:
+		t.Rounds = n - 1 + n%2
+	case Knockout:
+		t.Rounds = int(math.Ceil(math.Log2(float64(n))))
+	default:
+		if rounds == 0 {
+			rounds = int(math.Ceil(math.Log2(float64(n))))
+		}
+		if rounds < 1 || rounds > n-1+n%2 {
+			return nil, ErrRounds
+		}
+		t.Rounds = rounds
+	}
+	return t, nil
+}
+
+// Over reports whether every round has been played
+func (t *Tournament) Over() bool {
+	return t.Round == t.Rounds && t.RoundPlayed()
+}
+
+// RoundPlayed reports whether every game of the current round has been played
+func (t *Tournament) RoundPlayed() bool {
+	for _, p := range t.Pairings {
+		if p.Round == t.Round && !p.Played {
+			return false
+		}
+	}
+	return true
+}
+
+// NextRound pairs the players for the next round once the current one has been played.
+// Byes are already played.
+func (t *Tournament) NextRound() ([]Pairing, error) {
+	if t.Over() {
+		return nil, ErrOver
+	}
+	if !t.RoundPlayed() {
+		return nil, ErrRound
+	}
+	t.Round++
+	var pairs [][2]uint64
+	switch t.Format {
+	case RoundRobin:
+		pairs = t.roundRobin()
+	case Knockout:
+		pairs = t.knockout()
+	default:
+		pairs = t.swiss()
+	}
+	start := len(t.Pairings)
+	for _, pair := range pairs {
+		black, white := t.colors(pair[0], pair[1])
+		p := Pairing{Round: t.Round, Black: black, White: white}
+		if white == Bye {
+			p.Played, p.Score = true, 1
+		}
+		t.Pairings = append(t.Pairings, p)
+	}
+	return t.Pairings[start:], nil
+}
+
+// Record sets the score of the pairing at index i of Pairings
+func (t *Tournament) Record(i int, blackScore float64) error {
+	if i < 0 || i >= len(t.Pairings) {
+		return ErrPairing
+	}
+	if t.Pairings[i].Played {
+		return ErrPlayed
+	}
+	if blackScore != 0 && blackScore != 0.5 && blackScore != 1 {
+		return ErrScore
+	}
+	t.Pairings[i].Played, t.Pairings[i].Score = true, blackScore
+	return nil
+}
+
+// seed is the player's place among the entrants, 0 for the strongest
+func (t *Tournament) seed(player uint64) int {
+	for i, e := range t.Entrants {
+		if e.Player == player {
+			return i
+		}
+	}
+	return len(t.Entrants)
+}
+
+func (t *Tournament) players() []uint64 {
+	var players []uint64
+	for _, e := range t.Entrants {
+		players = append(players, e.Player)
+	}
+	return players
+}
+
+// colors gives Black to the player who has had it least, then to whoever had White last,
+// then to the higher seed in odd rounds
+func (t *Tournament) colors(a, b uint64) (uint64, uint64) {
+	switch {
+	case b == Bye:
+		return a, b
+	case a == Bye:
+		return b, a
+	}
+	balanceA, lastA := t.colorHistory(a)
+	balanceB, lastB := t.colorHistory(b)
+	switch {
+	case balanceA != balanceB:
+		if balanceA < balanceB {
+			return a, b
+		}
+		return b, a
+	case lastA != lastB:
+		if lastA < lastB {
+			return a, b
+		}
+		return b, a
+	case (t.Round%2 == 1) == (t.seed(a) < t.seed(b)):
+		return a, b
+	}
+	return b, a
+}
+
+// colorHistory returns how many more games player has played as Black than White,
+// and the color of their last game: 1 for Black, -1 for White, 0 for none
+func (t *Tournament) colorHistory(player uint64) (balance, last int) {
+	for _, p := range t.Pairings {
+		switch {
+		case p.White == Bye:
+		case p.Black == player:
+			balance++
+			last = 1
+		case p.White == player:
+			balance--
+			last = -1
+		}
+	}
+	return balance, last
+}
+
+// roundRobin pairs players with the circle method, which keeps the first player
+// in place and rotates the others by one each round
+func (t *Tournament) roundRobin() [][2]uint64 {
+	players := t.players()
+	if len(players)%2 == 1 {
+		players = append(players, Bye)
+	}
+	n := len(players)
+	k := (t.Round - 1) % (n - 1)
+	circle := []uint64{players[0]}
+	circle = append(circle, players[n-k:]...)
+	circle = append(circle, players[1:n-k]...)
+	var pairs [][2]uint64
+	for i := 0; i < n/2; i++ {
+		pairs = append(pairs, [2]uint64{circle[i], circle[n-1-i]})
+	}
+	return pairs
+}
+
+// played reports whether a and b have already been paired
+func (t *Tournament) played(a, b uint64) bool {
+	for _, p := range t.Pairings {
+		if (p.Black == a && p.White == b) || (p.Black == b && p.White == a) {
+			return true
+		}
+	}
+	return false
+}
+
+// swiss pairs players in order of score, with the top half of each score group
+// playing the bottom half. Rematches are avoided where possible.
+func (t *Tournament) swiss() [][2]uint64 {
+	scores := t.scores()
+	players := t.players()
+	sort.SliceStable(players, func(i, j int) bool {
+		return scores[players[i]] > scores[players[j]]
+	})
+	var pairs [][2]uint64
+	if len(players)%2 == 1 {
+		// The lowest player without a bye sits out
+		bye := len(players) - 1
+		for i := bye; i >= 0; i-- {
+			if !t.played(players[i], Bye) {
+				bye = i
+				break
+			}
+		}
+		pairs = append(pairs, [2]uint64{players[bye], Bye})
+		players = append(players[:bye:bye], players[bye+1:]...)
+	}
+	for _, rematches := range []bool{false, true} {
+		if found, ok := t.pairSwiss(players, scores, rematches); ok {
+			return append(pairs, found...)
+		}
+	}
+	return pairs
+}
+
+// pairSwiss pairs the first player with the best opponent that lets the rest be paired
+func (t *Tournament) pairSwiss(players []uint64, scores map[uint64]float64, rematches bool) ([][2]uint64, bool) {
+	if len(players) == 0 {
+		return nil, true
+	}
+	first := players[0]
+	group := 1
+	for group < len(players) && scores[players[group]] == scores[first] {
+		group++
+	}
+	// The top of the bottom half of the group first, then down and up the group, then lower groups
+	var order []int
+	for i := group / 2; i < len(players); i++ {
+		if i > 0 {
+			order = append(order, i)
+		}
+		if i == group-1 {
+			for j := group/2 - 1; j > 0; j-- {
+				order = append(order, j)
+			}
+		}
+	}
+	for _, i := range order {
+		opponent := players[i]
+		if !rematches && t.played(first, opponent) {
+			continue
+		}
+		rest := make([]uint64, 0, len(players)-2)
+		rest = append(rest, players[1:i]...)
+		rest = append(rest, players[i+1:]...)
+		if pairs, ok := t.pairSwiss(rest, scores, rematches); ok {
+			return append([][2]uint64{{first, opponent}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// bracket is the order of seeds in a knockout of size players, so the top seeds meet last
+func bracket(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		n := len(order) * 2
+		var next []int
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+// knockout pairs the seeds by bracket in the first round, and then the winners of neighbouring pairings
+func (t *Tournament) knockout() [][2]uint64 {
+	var players []uint64
+	if t.Round == 1 {
+		size := 1 << uint(t.Rounds)
+		for _, s := range bracket(size) {
+			if s <= len(t.Entrants) {
+				players = append(players, t.Entrants[s-1].Player)
+			} else {
+				players = append(players, Bye)
+			}
+		}
+	} else {
+		for _, p := range t.Pairings {
+			if p.Round == t.Round-1 {
+				players = append(players, t.winner(p))
+			}
+		}
+	}
+	var pairs [][2]uint64
+	for i := 0; i+1 < len(players); i += 2 {
+		pairs = append(pairs, [2]uint64{players[i], players[i+1]})
+	}
+	return pairs
+}
+
+// winner is the player going through from a pairing, the higher seed after a draw
+func (t *Tournament) winner(p Pairing) uint64 {
+	switch {
+	case p.White == Bye || p.Score == 1:
+		return p.Black
+	case p.Score == 0:
+		return p.White
+	case t.seed(p.Black) < t.seed(p.White):
+		return p.Black
+	}
+	return p.White
+}
+
+// start is the score a player starts on, which is only below 0 for McMahon players below the bar
+func (t *Tournament) start(e Entrant) float64 {
+	if t.Format != McMahon || e.Rating >= t.Bar {
+		return 0
+	}
+	return -math.Ceil((t.Bar - e.Rating) / Band)
+}
+
+// scores are the players' starting scores plus a point for every win or bye and half for every draw
+func (t *Tournament) scores() map[uint64]float64 {
+	scores := map[uint64]float64{}
+	for _, e := range t.Entrants {
+		scores[e.Player] = t.start(e)
+	}
+	for _, p := range t.Pairings {
+		if p.Played {
+			scores[p.Black] += p.Score
+			if p.White != Bye {
+				scores[p.White] += 1 - p.Score
+			}
+		}
+	}
+	return scores
+}
+
+// Standing is a player's place in a tournament. SOS is the sum of their opponents' scores,
+// and SODOS the sum of the scores of the opponents they beat, with half for draws.
+type Standing struct {
+	Rank   int     `json:"rank"`
+	Player uint64  `json:"player"`
+	Score  float64 `json:"score"`
+	SOS    float64 `json:"sos"`
+	SODOS  float64 `json:"sodos"`
+	Games  int     `json:"games"`
+}
+
+// Standings ranks the players by score, then SOS, then SODOS, then seed
+func (t *Tournament) Standings() []Standing {
+	scores := t.scores()
+	standings := map[uint64]*Standing{}
+	var ranked []*Standing
+	for _, e := range t.Entrants {
+		s := &Standing{Player: e.Player, Score: scores[e.Player]}
+		standings[e.Player] = s
+		ranked = append(ranked, s)
+	}
+	for _, p := range t.Pairings {
+		if !p.Played || p.White == Bye {
+			continue
+		}
+		black, white := standings[p.Black], standings[p.White]
+		black.Games++
+		white.Games++
+		black.SOS += scores[p.White]
+		white.SOS += scores[p.Black]
+		black.SODOS += p.Score * scores[p.White]
+		white.SODOS += (1 - p.Score) * scores[p.Black]
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		switch {
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case a.SOS != b.SOS:
+			return a.SOS > b.SOS
+		}
+		return a.SODOS > b.SODOS
+	})
+	var result []Standing
+	for i, s := range ranked {
+		s.Rank = i + 1
+		result = append(result, *s)
+	}
+	return result
+}
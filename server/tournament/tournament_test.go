@@ -0,0 +1,249 @@
+package tournament
+
+import (
+	"reflect"
+	"testing"
+)
+
+func entrants(n int) []Entrant {
+	var e []Entrant
+	for i := 1; i <= n; i++ {
+		e = append(e, Entrant{Player: uint64(i), Rating: float64(2000 - 100*i)})
+	}
+	return e
+}
+
+// play runs the tournament to the end, with the higher seed always winning
+func play(t *testing.T, tour *Tournament) {
+	for {
+		for i, p := range tour.Pairings {
+			if p.Played {
+				continue
+			}
+			score := 0.0
+			if p.Black < p.White {
+				score = 1
+			}
+			if err := tour.Record(i, score); err != nil {
+				t.Fatalf("pairing %d: '%s'", i, err)
+			}
+		}
+		if tour.Over() {
+			return
+		}
+		if _, err := tour.NextRound(); err != nil {
+			t.Fatalf("round %d: '%s'", tour.Round, err)
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5, 6, 8} {
+		tour, err := New(RoundRobin, entrants(n), 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		play(t, tour)
+		met := map[[2]uint64]int{}
+		for _, p := range tour.Pairings {
+			if p.White == Bye {
+				continue
+			}
+			a, b := p.Black, p.White
+			if a > b {
+				a, b = b, a
+			}
+			met[[2]uint64{a, b}]++
+		}
+		if len(met) != n*(n-1)/2 {
+			t.Errorf("%d players: expected %d pairs to meet, got %d", n, n*(n-1)/2, len(met))
+		}
+		for pair, times := range met {
+			if times != 1 {
+				t.Errorf("%d players: %v met %d times", n, pair, times)
+			}
+		}
+		for _, e := range tour.Entrants {
+			if balance, _ := tour.colorHistory(e.Player); balance < -1 || balance > 1 {
+				t.Errorf("%d players: player %d has color balance %d", n, e.Player, balance)
+			}
+		}
+		// A bye counts as a win
+		for i, s := range tour.Standings() {
+			if s.Player != uint64(i+1) || s.Score != float64(n-1-i+n%2) {
+				t.Errorf("%d players: unexpected standing %+v", n, s)
+			}
+		}
+	}
+}
+
+func TestSwiss(t *testing.T) {
+	tour, err := New(Swiss, entrants(8), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tour.Rounds != 3 {
+		t.Fatalf("expected 3 rounds, got %d", tour.Rounds)
+	}
+	first, _ := tour.NextRound()
+	// Everyone is level, so the top half plays the bottom half
+	expected := [][2]uint64{{1, 5}, {2, 6}, {3, 7}, {4, 8}}
+	for i, p := range first {
+		if (p.Black != expected[i][0] || p.White != expected[i][1]) && (p.Black != expected[i][1] || p.White != expected[i][0]) {
+			t.Errorf("pairing %d: expected %v, got %+v", i, expected[i], p)
+		}
+	}
+	if _, err := tour.NextRound(); err != ErrRound {
+		t.Errorf("expected '%s' before the round is played, got '%v'", ErrRound, err)
+	}
+	play(t, tour)
+	if _, err := tour.NextRound(); err != ErrOver {
+		t.Errorf("expected '%s', got '%v'", ErrOver, err)
+	}
+	for i, p := range tour.Pairings {
+		for _, q := range tour.Pairings[i+1:] {
+			if (p.Black == q.Black && p.White == q.White) || (p.Black == q.White && p.White == q.Black) {
+				t.Errorf("unexpected rematch %+v %+v", p, q)
+			}
+		}
+	}
+	standings := tour.Standings()
+	if standings[0].Player != 1 || standings[0].Score != 3 || standings[7].Player != 8 || standings[7].Score != 0 {
+		t.Errorf("unexpected standings %+v", standings)
+	}
+}
+
+func TestSwissBye(t *testing.T) {
+	tour, _ := New(Swiss, entrants(5), 4, 0)
+	play(t, tour)
+	byes := map[uint64]int{}
+	for _, p := range tour.Pairings {
+		if p.White == Bye {
+			byes[p.Black]++
+		}
+	}
+	if len(byes) != 4 {
+		t.Errorf("expected byes to go to different players, got %v", byes)
+	}
+	if _, err := New(Swiss, entrants(4), 4, 0); err != ErrRounds {
+		t.Errorf("expected '%s', got '%v'", ErrRounds, err)
+	}
+}
+
+func TestMcMahon(t *testing.T) {
+	// Players 1-3 are at or above the bar of 1700, and the rest start a point lower for each band below it
+	tour, _ := New(McMahon, entrants(6), 2, 1700)
+	var starts []float64
+	for _, s := range tour.Standings() {
+		starts = append(starts, s.Score)
+	}
+	if expected := []float64{0, 0, 0, -1, -2, -3}; !reflect.DeepEqual(starts, expected) {
+		t.Errorf("expected starting scores %v, got %v", expected, starts)
+	}
+	first, _ := tour.NextRound()
+	// The bar group of three pairs its top two, and the third plays the next group
+	expected := [][2]uint64{{1, 2}, {3, 4}, {5, 6}}
+	for i, p := range first {
+		if (p.Black != expected[i][0] || p.White != expected[i][1]) && (p.Black != expected[i][1] || p.White != expected[i][0]) {
+			t.Errorf("pairing %d: expected %v, got %+v", i, expected[i], p)
+		}
+	}
+}
+
+func TestKnockout(t *testing.T) {
+	if b := bracket(8); !reflect.DeepEqual(b, []int{1, 8, 4, 5, 2, 7, 3, 6}) {
+		t.Errorf("unexpected bracket %v", b)
+	}
+	tour, _ := New(Knockout, entrants(6), 0, 0)
+	if tour.Rounds != 3 {
+		t.Fatalf("expected 3 rounds, got %d", tour.Rounds)
+	}
+	first, _ := tour.NextRound()
+	byes := 0
+	for _, p := range first {
+		if p.White == Bye {
+			byes++
+			if p.Black != 1 && p.Black != 2 {
+				t.Errorf("expected the top seeds to get byes, got %+v", p)
+			}
+		}
+	}
+	if byes != 2 {
+		t.Errorf("expected 2 byes, got %d", byes)
+	}
+	// Seed 4 wins an upset, and goes through to meet seed 1
+	for i, p := range tour.Pairings {
+		if !p.Played {
+			tour.Record(i, map[bool]float64{true: 1, false: 0}[p.Black == 4 || (p.White != 4 && p.Black < p.White)])
+		}
+	}
+	second, _ := tour.NextRound()
+	var players []uint64
+	for _, p := range second {
+		players = append(players, p.Black, p.White)
+	}
+	for _, expected := range []uint64{1, 4, 2, 3} {
+		found := false
+		for _, p := range players {
+			found = found || p == expected
+		}
+		if !found {
+			t.Errorf("expected %d in the second round, got %+v", expected, second)
+		}
+	}
+	play(t, tour)
+	if s := tour.Standings(); s[0].Player != 1 {
+		t.Errorf("expected seed 1 to win, got %+v", s)
+	}
+}
+
+func TestTieBreakers(t *testing.T) {
+	// 1 beats 2, 2 beats 3, 3 beats 1, and 4 loses to everyone
+	tour := &Tournament{Format: RoundRobin, Entrants: entrants(4), Rounds: 3, Round: 3, Pairings: []Pairing{
+		{1, 1, 2, true, 1}, {1, 3, 4, true, 1},
+		{2, 2, 3, true, 1}, {2, 4, 1, true, 0},
+		{3, 3, 1, true, 1}, {3, 2, 4, true, 1},
+	}}
+	expected := []Standing{
+		{1, 1, 2, 4, 2, 3},
+		{2, 2, 2, 4, 2, 3},
+		{3, 3, 2, 4, 2, 3},
+		{4, 4, 0, 6, 0, 3},
+	}
+	if s := tour.Standings(); !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+	// When 4 beats 2, 3 and 1 are level on SOS and 3 beat the stronger opponents
+	tour.Pairings[5].Score = 0
+	var order []uint64
+	for _, s := range tour.Standings() {
+		order = append(order, s.Player)
+	}
+	if expected := []uint64{3, 1, 2, 4}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, e := range [][]Entrant{
+		entrants(1),
+		{{1, 0}, {1, 0}},
+		{{0, 0}, {1, 0}},
+	} {
+		if _, err := New(RoundRobin, e, 0, 0); err != ErrEntrants {
+			t.Errorf("%v: expected '%s', got '%v'", e, ErrEntrants, err)
+		}
+	}
+	tour, _ := New(RoundRobin, entrants(2), 0, 0)
+	tour.NextRound()
+	if err := tour.Record(0, 0.7); err != ErrScore {
+		t.Errorf("expected '%s', got '%v'", ErrScore, err)
+	}
+	tour.Record(0, 1)
+	if err := tour.Record(0, 1); err != ErrPlayed {
+		t.Errorf("expected '%s', got '%v'", ErrPlayed, err)
+	}
+	if err := tour.Record(1, 1); err != ErrPairing {
+		t.Errorf("expected '%s', got '%v'", ErrPairing, err)
+	}
+}
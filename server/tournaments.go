@@ -0,0 +1,302 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server/tournament"
+)
+
+// event is a tournament being played on the server. Its games are created
+// as each round is paired, and tournaments are only kept in memory.
+type event struct {
+	ID       uint64   `json:"id"`
+	Name     string   `json:"name,omitempty"`
+	Settings Settings `json:"settings"`
+	*tournament.Tournament
+	// games are the games of each pairing, nil for byes
+	games []*Game
+	// seats are the GameIDs of the players of each pairing
+	seats []map[game.Color]GameID
+}
+
+// scheduledPairing is a pairing with the number of the game it is played in
+type scheduledPairing struct {
+	tournament.Pairing
+	Game uint64 `json:"game,omitempty"`
+}
+
+// eventSummary describes a tournament in the list of tournaments
+type eventSummary struct {
+	ID     uint64            `json:"id"`
+	Name   string            `json:"name,omitempty"`
+	Format tournament.Format `json:"format"`
+	Round  int               `json:"round"`
+	Rounds int               `json:"rounds"`
+	Over   bool              `json:"over"`
+}
+
+// assignment is a player's game in the current round of a tournament
+type assignment struct {
+	Round int        `json:"round"`
+	ID    GameID     `json:"ID,omitempty"`
+	Color game.Color `json:"color,omitempty"`
+	Game  uint64     `json:"game,omitempty"`
+	// Waiting is set while the player has no game until the rest of the round is played
+	Waiting bool `json:"waiting,omitempty"`
+	// Over is set once the tournament has ended
+	Over bool `json:"over,omitempty"`
+}
+
+// The tournament methods below must be called with a.mu held
+
+// schedule pairs the next round of e and creates its games
+func (a *api) schedule(e *event) error {
+	start := len(e.Pairings)
+	pairings, err := e.NextRound()
+	if err != nil {
+		return err
+	}
+	for i, p := range pairings {
+		e.games = append(e.games, nil)
+		e.seats = append(e.seats, map[game.Color]GameID{})
+		if p.White == tournament.Bye {
+			continue
+		}
+		g, err := a.store.Create(e.Settings, Listing{})
+		if err != nil {
+			return err
+		}
+		a.created(g, e.Settings)
+		g.event, g.pairing = e, start+i
+		for c, player := range map[game.Color]uint64{game.Black: p.Black, game.White: p.White} {
+			id, err := a.store.Join(g, c, player)
+			if err != nil {
+				return err
+			}
+			a.joined(g, id, c, player)
+			e.seats[start+i][c] = id
+		}
+		e.games[start+i] = g
+		g.mu.Lock()
+		a.startClock(g)
+		g.mu.Unlock()
+	}
+	return nil
+}
+
+// finishPairing records the result of a tournament game once it has ended,
+// and starts the next round once every game of the round has been played
+func (a *api) finishPairing(g *Game) {
+	if g.event == nil {
+		return
+	}
+	g.mu.Lock()
+	result, over := g.state.Result()
+	g.mu.Unlock()
+	if !over {
+		return
+	}
+	score := 0.5
+	switch result.Winner {
+	case game.Black:
+		score = 1
+	case game.White:
+		score = 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	e := g.event
+	if err := e.Record(g.pairing, score); err != nil {
+		return
+	}
+	if e.RoundPlayed() && !e.Over() {
+		if err := a.schedule(e); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// lookupEvent finds a tournament by the number in a request path
+func (a *api) lookupEvent(s string) (*event, error) {
+	n, _ := strconv.ParseUint(s, 10, 64)
+	if n < 1 || n > uint64(len(a.events)) {
+		return nil, newError(CodeUnknownTournament, "Tournament %s does not exist", s)
+	}
+	return a.events[n-1], nil
+}
+
+// tournamentsHandler lists tournaments at tournaments/, creates them with
+// tournaments/create?format=swiss&players=1,2,3,4&rounds=3 and the settings of their games,
+// and describes them at tournaments/<id>, tournaments/<id>/standings and tournaments/<id>/next,
+// which a player polls for their game in the current round
+func (a *api) tournamentsHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch parts[0] {
+	case "":
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		list := []eventSummary{}
+		for _, e := range a.events {
+			list = append(list, eventSummary{e.ID, e.Name, e.Format, e.Round, e.Rounds, e.Over()})
+		}
+		writeJSON(w, list)
+		return
+	case "create":
+		a.createTournament(w, r)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	e, err := a.lookupEvent(parts[0])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	switch action {
+	case "":
+		pairings := []scheduledPairing{}
+		for i, p := range e.Pairings {
+			s := scheduledPairing{Pairing: p}
+			if e.games[i] != nil {
+				s.Game = e.games[i].id
+			}
+			pairings = append(pairings, s)
+		}
+		writeJSON(w, struct {
+			*event
+			Pairings []scheduledPairing `json:"pairings"`
+		}{e, pairings})
+	case "standings":
+		writeJSON(w, e.Standings())
+	case "next":
+		a.nextHandler(e, w, r)
+	default:
+		writeError(w, r, newError(CodeUnknownAction, "%s is not a valid tournament action", action))
+	}
+}
+
+// nextHandler writes the requesting player's game in the current round
+func (a *api) nextHandler(e *event, w http.ResponseWriter, r *http.Request) {
+	p, err := a.authenticate(r)
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case p == nil:
+		writeError(w, r, newError(CodeUnauthorized, "Send the API key of an entrant in %s", KeyHeader))
+		return
+	}
+	entered := false
+	for _, entrant := range e.Entrants {
+		entered = entered || entrant.Player == p.ID
+	}
+	if !entered {
+		writeError(w, r, newError(CodeForbidden, "%s has not entered tournament %d", p.Name, e.ID))
+		return
+	}
+	if e.Over() {
+		writeJSON(w, assignment{Round: e.Round, Over: true})
+		return
+	}
+	for i, pairing := range e.Pairings {
+		if pairing.Round != e.Round || pairing.Played || e.games[i] == nil {
+			continue
+		}
+		for c, player := range map[game.Color]uint64{game.Black: pairing.Black, game.White: pairing.White} {
+			if player == p.ID {
+				writeJSON(w, assignment{Round: e.Round, ID: e.seats[i][c], Color: c, Game: e.games[i].id})
+				return
+			}
+		}
+	}
+	writeJSON(w, assignment{Round: e.Round, Waiting: true})
+}
+
+func (a *api) createTournament(w http.ResponseWriter, r *http.Request) {
+	p, err := a.authenticate(r)
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case p == nil:
+		writeError(w, r, newError(CodeUnauthorized, "Only registered players can create tournaments, send an API key in %s", KeyHeader))
+		return
+	}
+	format, err := tournament.ParseFormat(r.FormValue("format"))
+	if err != nil {
+		writeError(w, r, Error{Code: CodeBadRequest, Message: err.Error()})
+		return
+	}
+	s, err := parseSettings(r.Form)
+	if err != nil {
+		writeError(w, r, Error{Code: CodeBadRules, Message: err.Error()})
+		return
+	}
+	var rounds int
+	var bar float64
+	if v := r.FormValue("rounds"); v != "" {
+		if rounds, err = strconv.Atoi(v); err != nil {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a valid number of rounds", v))
+			return
+		}
+	}
+	if v := r.FormValue("bar"); v != "" {
+		if bar, err = strconv.ParseFloat(v, 64); err != nil {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a valid McMahon bar", v))
+			return
+		}
+	}
+	// Entrants are bots, seeded by their rating on the board size
+	var entrants []tournament.Entrant
+	for _, v := range strings.Split(r.FormValue("players"), ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a player", v))
+			return
+		}
+		player, err := a.store.Player(id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if player.Kind != Bot {
+			writeError(w, r, newError(CodeBadRequest, "%s is not a bot", player.Name))
+			return
+		}
+		a.ratings.Lock()
+		latest, err := a.latest(id, s.Size)
+		a.ratings.Unlock()
+		if err != nil {
+			writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+			return
+		}
+		entrants = append(entrants, tournament.Entrant{Player: id, Rating: latest.Rating.Rating})
+	}
+	sort.SliceStable(entrants, func(i, j int) bool {
+		return entrants[i].Rating > entrants[j].Rating
+	})
+	t, err := tournament.New(format, entrants, rounds, bar)
+	if err != nil {
+		writeError(w, r, Error{Code: CodeBadRequest, Message: err.Error()})
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	e := &event{ID: uint64(len(a.events) + 1), Name: r.FormValue("name"), Settings: s, Tournament: t}
+	a.events = append(a.events, e)
+	if err := a.schedule(e); err != nil {
+		writeError(w, r, Error{Code: CodeInternal, Message: err.Error()})
+		return
+	}
+	writeJSON(w, eventSummary{e.ID, e.Name, e.Format, e.Round, e.Rounds, e.Over()})
+}
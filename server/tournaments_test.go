@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gophergala2016/gobotgo/game"
+	"github.com/gophergala2016/gobotgo/server/tournament"
+)
+
+// playRound polls each bot for its game, and has the lower numbered player of each game win
+func playRound(t *testing.T, url string, id string, bots []registration) {
+	type seat struct {
+		assignment
+		registration
+	}
+	games := map[uint64]map[game.Color]seat{}
+	for _, b := range bots {
+		var next assignment
+		if status := getJSON(t, url+"/api/v2/tournaments/"+id+"/next", b.Key, &next); status != http.StatusOK {
+			t.Fatalf("%s: unable to get next game: %d", b.Player.Name, status)
+		}
+		if next.Waiting || next.Over {
+			continue
+		}
+		if games[next.Game] == nil {
+			games[next.Game] = map[game.Color]seat{}
+		}
+		games[next.Game][next.Color] = seat{next, b}
+	}
+	for _, seats := range games {
+		loser := seats[game.White]
+		if seats[game.White].Player.ID < seats[game.Black].Player.ID {
+			loser = seats[game.Black]
+		}
+		var result game.Result
+		if status := getJSON(t, url+"/api/v2/game/play/"+string(loser.ID)+"/resign", loser.Key, &result); status != http.StatusOK {
+			t.Fatalf("unable to resign %s: %d", loser.ID, status)
+		}
+	}
+}
+
+func TestTournaments(t *testing.T) {
+	defer sequentialIDs()()
+	ts := httptest.NewServer(MuxerAPI(NewMemoryStore()))
+	defer ts.Close()
+	var bots []registration
+	for _, name := range []string{"one", "two", "three", "four"} {
+		bots = append(bots, register(t, ts.URL, "kind=bot&name="+name))
+	}
+	human := register(t, ts.URL, "name=ann")
+
+	errors := []struct {
+		query, key string
+		status     int
+		code       ErrorCode
+	}{
+		{"format=swiss&players=1,2", "", http.StatusUnauthorized, CodeUnauthorized},
+		{"format=cup&players=1,2", human.Key, http.StatusBadRequest, CodeBadRequest},
+		{"players=1,5", human.Key, http.StatusBadRequest, CodeBadRequest},
+		{"players=1,9", human.Key, http.StatusNotFound, CodeUnknownPlayer},
+		{"players=1", human.Key, http.StatusBadRequest, CodeBadRequest},
+		{"format=swiss&rounds=9&players=1,2", human.Key, http.StatusBadRequest, CodeBadRequest},
+	}
+	for _, test := range errors {
+		var e Error
+		if status := getJSON(t, ts.URL+"/api/v2/tournaments/create?"+test.query, test.key, &e); status != test.status || e.Code != test.code {
+			t.Errorf("%s: expected %d %s, got %d %+v", test.query, test.status, test.code, status, e)
+		}
+	}
+
+	for _, test := range []struct {
+		format tournament.Format
+		rounds int
+	}{
+		{tournament.RoundRobin, 3},
+		{tournament.Swiss, 2},
+		{tournament.Knockout, 2},
+	} {
+		var summary eventSummary
+		query := "size=9&name=cup&players=1,2,3,4&format=" + test.format.String()
+		if status := getJSON(t, ts.URL+"/api/v2/tournaments/create?"+query, human.Key, &summary); status != http.StatusOK {
+			t.Fatalf("%s: unable to create tournament: %d", test.format, status)
+		}
+		id := strconv.FormatUint(summary.ID, 10)
+		if summary.Rounds != test.rounds || summary.Round != 1 {
+			t.Errorf("%s: expected round 1 of %d, got %+v", test.format, test.rounds, summary)
+		}
+		var e Error
+		if status := getJSON(t, ts.URL+"/api/v2/tournaments/"+id+"/next", human.Key, &e); status != http.StatusForbidden || e.Code != CodeForbidden {
+			t.Errorf("%s: expected a player who has not entered to be refused, got %d %+v", test.format, status, e)
+		}
+		for round := 1; round <= test.rounds; round++ {
+			playRound(t, ts.URL, id, bots)
+		}
+		var next assignment
+		getJSON(t, ts.URL+"/api/v2/tournaments/"+id+"/next", bots[0].Key, &next)
+		if !next.Over {
+			t.Errorf("%s: expected the tournament to be over, got %+v", test.format, next)
+		}
+		var standings []tournament.Standing
+		getJSON(t, ts.URL+"/api/v2/tournaments/"+id+"/standings", "", &standings)
+		if len(standings) != 4 || standings[0].Player != 1 || standings[0].Score != float64(test.rounds) {
+			t.Errorf("%s: expected player 1 to win every game, got %+v", test.format, standings)
+		}
+	}
+
+	var list []eventSummary
+	getJSON(t, ts.URL+"/api/v2/tournaments/", "", &list)
+	if len(list) != 3 || !list[0].Over || list[2].Format != tournament.Knockout {
+		t.Errorf("unexpected tournaments %+v", list)
+	}
+	var e Error
+	if status := getJSON(t, ts.URL+"/api/v2/tournaments/4", "", &e); status != http.StatusNotFound || e.Code != CodeUnknownTournament {
+		t.Errorf("expected unknown_tournament, got %d %+v", status, e)
+	}
+}
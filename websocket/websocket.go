@@ -0,0 +1,400 @@
+// Package websocket implements the parts of the WebSocket protocol (RFC 6455)
+// the game channel needs: text and binary messages, fragmentation, ping and close.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// guid is appended to the client's key to compute the accept header
+const guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxMessage is the largest message a Conn reads
+const MaxMessage = 1 << 20
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+var (
+	// ErrClosed is returned by ReadMessage once the connection has been closed by either side
+	ErrClosed   = errors.New("websocket: connection closed")
+	ErrTooLarge = errors.New("websocket: message too large")
+	ErrProtocol = errors.New("websocket: protocol error")
+)
+
+// HandshakeError is a request that is not a valid WebSocket handshake
+type HandshakeError struct {
+	Message string
+}
+
+func (e HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// Conn is a WebSocket connection. One goroutine may read while others write.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	// client connections mask the frames they send
+	client bool
+	wmu    sync.Mutex
+	closed bool
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + guid))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Upgrade completes the handshake of a WebSocket request and takes over its connection.
+// It answers requests that are not valid handshakes with 400 Bad Request.
+// The Origin header is not checked: requests from any site are upgraded, as the game
+// channel is only authenticated by the GameID and API key a page would have to know.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	var err error
+	switch {
+	case r.Method != "GET":
+		err = HandshakeError{"handshake must be a GET request"}
+	case !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket"):
+		err = HandshakeError{"not a websocket upgrade request"}
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		err = HandshakeError{"unsupported version " + r.Header.Get("Sec-WebSocket-Version")}
+	case key == "":
+		err = HandshakeError{"missing Sec-WebSocket-Key"}
+	}
+	if err != nil {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, err
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, HandshakeError{"response does not support hijacking"}
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, r: rw.Reader}, nil
+}
+
+// Dial opens a WebSocket connection to a ws:// URL, sending the extra header
+func Dial(rawurl string, header http.Header) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ws" {
+		return nil, fmt.Errorf("websocket: unsupported scheme %s", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host += ":80"
+	}
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{
+		Method:     "GET",
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       u.Host,
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		conn.Close()
+		return nil, &DialError{resp.StatusCode, body}
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, HandshakeError{"server sent the wrong Sec-WebSocket-Accept"}
+	}
+	return &Conn{conn: conn, r: r, client: true}, nil
+}
+
+// DialError is a handshake refused by the server, with the body of its response
+type DialError struct {
+	Status int
+	Body   []byte
+}
+
+func (e *DialError) Error() string {
+	return fmt.Sprintf("websocket: handshake refused with status %d", e.Status)
+}
+
+// writeFrame sends a message in a single frame
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	return c.writeFragment(true, op, payload)
+}
+
+// writeFragment sends a frame, the last of its message if fin is set,
+// masked if the connection is a client's
+func (c *Conn) writeFragment(fin bool, op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	header := []byte{op, 0}
+	if fin {
+		header[0] |= 0x80
+	}
+	n := len(payload)
+	switch {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	if c.client {
+		header[1] |= 0x80
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		header = append(header, mask...)
+		masked := make([]byte, n)
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+	if op == opClose {
+		c.closed = true
+	}
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// readFrame reads a single frame, unmasking its payload
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.r, header); err != nil {
+		return
+	}
+	fin, op = header[0]&0x80 != 0, header[0]&0x0F
+	if header[0]&0x70 != 0 {
+		err = ErrProtocol
+		return
+	}
+	masked := header[1]&0x80 != 0
+	// Clients must mask their frames and servers must not
+	if masked == c.client {
+		err = ErrProtocol
+		return
+	}
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		b := make([]byte, 2)
+		if _, err = io.ReadFull(c.r, b); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(b))
+	case 127:
+		b := make([]byte, 8)
+		if _, err = io.ReadFull(c.r, b); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(b)
+	}
+	// Control frames cannot be fragmented and carry at most 125 bytes
+	if op&0x8 != 0 && (!fin || n > 125) {
+		err = ErrProtocol
+		return
+	}
+	if n > MaxMessage {
+		err = ErrTooLarge
+		return
+	}
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(c.r, mask); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// ReadMessage returns the next text or binary message, answering pings and
+// joining fragments on the way. It returns ErrClosed once either side has closed.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF || errors.Is(err, net.ErrClosed) {
+				return nil, ErrClosed
+			}
+			if err == ErrProtocol || err == ErrTooLarge {
+				c.closeWith(1002)
+			}
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// Echo the close, unless this side closed first
+			c.writeFrame(opClose, nil)
+			c.conn.Close()
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				c.closeWith(1002)
+				return nil, ErrProtocol
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				c.closeWith(1002)
+				return nil, ErrProtocol
+			}
+		default:
+			c.closeWith(1002)
+			return nil, ErrProtocol
+		}
+		if len(message)+len(payload) > MaxMessage {
+			c.closeWith(1009)
+			return nil, ErrTooLarge
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// WriteMessage sends a text message
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Ping sends a ping, which the other side answers with a pong
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// ReadJSON reads the next message into v
+func (c *Conn) ReadJSON(v interface{}) error {
+	data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteJSON sends v as a text message
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(data)
+}
+
+func (c *Conn) closeWith(status uint16) error {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, status)
+	return c.writeFrame(opClose, payload)
+}
+
+// Close sends a normal close and closes the connection
+func (c *Conn) Close() error {
+	c.closeWith(1000)
+	return c.conn.Close()
+}
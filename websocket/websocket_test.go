@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echo serves a WebSocket that sends back every message it receives
+func echo(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			m, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(m); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}))
+}
+
+func dial(t *testing.T, ts *httptest.Server) *Conn {
+	c, err := Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unable to dial: '%s'", err)
+	}
+	return c
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455 section 1.3
+	if k := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); k != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept key %s", k)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	ts := echo(t)
+	defer ts.Close()
+	c := dial(t, ts)
+	defer c.Close()
+	// Lengths with 7, 16 and 64 bit encodings
+	for _, n := range []int{0, 5, 125, 126, 1000, 70000} {
+		m := bytes.Repeat([]byte("g"), n)
+		if err := c.WriteMessage(m); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Ping(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("%d bytes: '%s'", n, err)
+		}
+		if !bytes.Equal(got, m) {
+			t.Errorf("%d bytes: got %d bytes back", n, len(got))
+		}
+	}
+	var v struct{ Move []int }
+	if err := c.WriteJSON(struct{ Move []int }{[]int{3, 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReadJSON(&v); err != nil || len(v.Move) != 2 || v.Move[1] != 4 {
+		t.Errorf("unexpected JSON %+v '%v'", v, err)
+	}
+}
+
+func TestFragments(t *testing.T) {
+	ts := echo(t)
+	defer ts.Close()
+	c := dial(t, ts)
+	defer c.Close()
+	// A message in three frames, with a ping in the middle
+	for _, f := range []struct {
+		fin     bool
+		op      byte
+		payload string
+	}{
+		{false, opText, "go "},
+		{false, opContinuation, "bot "},
+		{true, opPing, ""},
+		{true, opContinuation, "go"},
+	} {
+		if err := c.writeFragment(f.fin, f.op, []byte(f.payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := c.ReadMessage()
+	if err != nil || string(m) != "go bot go" {
+		t.Errorf("expected the joined message, got %q '%v'", m, err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	ts := echo(t)
+	defer ts.Close()
+	type frame struct {
+		fin     bool
+		op      byte
+		payload string
+	}
+	tests := []struct {
+		name   string
+		frames []frame
+	}{
+		{"fragmented ping", []frame{{false, opPing, ""}}},
+		{"long ping", []frame{{true, opPing, strings.Repeat("p", 126)}}},
+		{"unknown opcode", []frame{{true, 0x3, "go"}}},
+		{"continuation first", []frame{{true, opContinuation, "go"}}},
+		{"message in a message", []frame{{false, opText, "go "}, {true, opText, "bot"}}},
+	}
+	for _, test := range tests {
+		c := dial(t, ts)
+		for _, f := range test.frames {
+			if err := c.writeFragment(f.fin, f.op, []byte(f.payload)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The server closes with 1002 protocol error
+		_, op, payload, err := c.readFrame()
+		if err != nil || op != opClose || len(payload) != 2 || payload[0] != 0x03 || payload[1] != 0xEA {
+			t.Errorf("%s: expected a 1002 close, got op %d %v '%v'", test.name, op, payload, err)
+		}
+		c.conn.Close()
+	}
+}
+
+func TestClose(t *testing.T) {
+	ts := echo(t)
+	defer ts.Close()
+	c := dial(t, ts)
+	c.WriteMessage([]byte("bye"))
+	c.Close()
+	if err := c.WriteMessage([]byte("again")); err != ErrClosed {
+		t.Errorf("expected '%s' writing after close, got '%v'", ErrClosed, err)
+	}
+}
+
+func TestBadHandshake(t *testing.T) {
+	ts := echo(t)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a plain request to be refused, got %s", resp.Status)
+	}
+	if _, err := Dial("http"+strings.TrimPrefix(ts.URL, "http"), nil); err == nil {
+		t.Error("expected http URLs to be refused")
+	}
+}